@@ -0,0 +1,118 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type activateCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	strategy contextmanager.Strategy
+}
+
+// NewActivateCmd returns the `activate` subcommand that installs the active environment into the provider locations.
+func NewActivateCmd() *cobra.Command {
+	a := &activateCmd{
+		logger: slog.Default().WithGroup("activate"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "activate",
+		Short: "Install the active context environment into the provider locations",
+		Long: `Install the active context environment into the provider locations.
+
+The active environment is selected by the nearest ` + contextmanager.LocalVersionFileName + ` file of the current
+directory and its parents, or by the global environment.
+
+The files are installed as symbolic links into the llmctxenv root, hard links, or copies. The strategy is
+taken from the --strategy flag, or from the "providers.<provider>.strategy" and "strategy" keys of the
+config.json file in the llmctxenv root, and defaults to symlink.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.RunE = a.RunActivate
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&a.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.StringVarP((*string)(&a.strategy), "strategy", "s", "", "activation strategy: symlink, hardlink or copy (default from config)")
+
+	return cmd
+}
+
+// RunActivate runs the `activate` subcommand which installs the context files of the active environment
+// into the location where each provider CLI reads them.
+func (c *activateCmd) RunActivate(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunActivate",
+		slog.String("provider", c.provider.String()),
+		slog.String("strategy", c.strategy.String()),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+	if c.strategy != "" {
+		if _, err := contextmanager.ParseStrategy(c.strategy.String()); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := contextmanager.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	// A broken provider must not prevent the others from being activated
+	var errs []error
+	w := cmd.OutOrStdout()
+	for _, provider := range providers {
+		env, err := contextmanager.ResolveEnvironment(provider)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolve %s environment: %w", provider, err))
+			continue
+		}
+
+		strategy := cmp.Or(c.strategy, cfg.StrategyFor(provider))
+		d, err := contextmanager.Activate(env, strategy)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("activate %s environment %s: %w", provider, env.Name, err))
+			continue
+		}
+		if d == nil {
+			continue
+		}
+
+		c.logger.DebugContext(cmd.Context(), "activated",
+			slog.String("provider", provider.String()),
+			slog.String("environment", env.Name),
+			slog.String("strategy", strategy.String()),
+		)
+		for _, f := range d.Files {
+			fmt.Fprintf(w, "%s: %s -> %s (%s)\n", provider, env.Name, f.Target, d.Strategy)
+		}
+	}
+
+	return errors.Join(errs...)
+}
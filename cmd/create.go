@@ -0,0 +1,108 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type createCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	shared   bool
+}
+
+// NewCreateCmd returns the `create` subcommand that creates a named context environment.
+func NewCreateCmd() *cobra.Command {
+	c := &createCmd{
+		logger: slog.Default().WithGroup("create"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a named context environment",
+		Long: `Create a named context environment.
+
+With --shared, the environment is created for every provider. Its ` + contextmanager.CanonicalFile + ` file is rendered into the
+context file of each provider, such as CLAUDE.md or AGENTS.md, on activation. The context file of a provider
+environment of the same name is appended to it, so that it holds the provider specific instructions.
+
+The sections which apply only to some providers are written between markers on their own lines:
+
+  <!-- llmctxenv:if provider=claude,codex -->
+  Instructions for Claude Code and Codex.
+  <!-- llmctxenv:end -->
+
+  <!-- llmctxenv:if not provider=goose -->
+  Instructions for every provider but Goose.
+  <!-- llmctxenv:end -->
+
+The markers are processed only in the environments rendered from a ` + contextmanager.CanonicalFile + ` file, in both the
+` + contextmanager.CanonicalFile + ` file and the provider context files appended to it. The activation of any other
+environment whose context files contain a marker fails, rather than installing the markers verbatim.`,
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.RunE = c.RunCreate
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&c.provider), "provider", "p", "", "manages system context provider name")
+	f.BoolVar(&c.shared, "shared", false, "create the environment shared by every provider")
+
+	return cmd
+}
+
+// RunCreate runs the `create` subcommand which creates the named environment of the provider.
+func (c *createCmd) RunCreate(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	c.logger.DebugContext(cmd.Context(), "RunCreate",
+		slog.String("name", name),
+		slog.String("provider", c.provider.String()),
+		slog.Bool("shared", c.shared),
+	)
+
+	if c.shared {
+		if c.provider != "" {
+			return fmt.Errorf("--shared environment cannot be created for a provider")
+		}
+		dir, err := contextmanager.CreateSharedEnvironment(name)
+		if err != nil {
+			return fmt.Errorf("create environment: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "created shared environment %s: %s\n", name, filepath.Join(dir, contextmanager.CanonicalFile))
+		return nil
+	}
+
+	if err := requireProvider(c.provider); err != nil {
+		return err
+	}
+
+	env, err := contextmanager.CreateEnvironment(c.provider, name)
+	if err != nil {
+		return fmt.Errorf("create environment: %w", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "created %s environment %s: %s\n", env.Provider, env.Name, env.Dir)
+
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type deactivateCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	force    bool
+}
+
+// NewDeactivateCmd returns the `deactivate` subcommand that removes the installed context files from the provider locations.
+func NewDeactivateCmd() *cobra.Command {
+	d := &deactivateCmd{
+		logger: slog.Default().WithGroup("deactivate"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "deactivate",
+		Short: "Remove the installed context files and restore the original files",
+		Args:  cobra.NoArgs,
+	}
+	cmd.RunE = d.RunDeactivate
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&d.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.BoolVarP(&d.force, "force", "f", false, "move installed files modified since activation into the backup directory instead of failing")
+
+	return cmd
+}
+
+// RunDeactivate runs the `deactivate` subcommand which removes the context files installed by `activate`
+// and restores the files which were there before.
+func (c *deactivateCmd) RunDeactivate(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunDeactivate",
+		slog.String("provider", c.provider.String()),
+		slog.Bool("force", c.force),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	for _, provider := range providers {
+		d, saved, err := contextmanager.Deactivate(provider, c.force)
+		for target, b := range saved {
+			fmt.Fprintf(w, "%s: saved modified %s to %s\n", provider, target, b.Path())
+		}
+		if err != nil {
+			if errors.Is(err, contextmanager.ErrModifiedFile) {
+				err = fmt.Errorf("%w (use --force to save it to the backup directory)", err)
+			}
+			return fmt.Errorf("deactivate %s: %w", provider, err)
+		}
+		if d == nil {
+			continue
+		}
+
+		for _, f := range d.Files {
+			if f.Backup != nil {
+				fmt.Fprintf(w, "%s: restored %s\n", provider, f.Target)
+				continue
+			}
+			fmt.Fprintf(w, "%s: removed %s\n", provider, f.Target)
+		}
+	}
+
+	return nil
+}
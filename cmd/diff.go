@@ -0,0 +1,136 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type diffCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	exitCode bool
+}
+
+// NewDiffCmd returns the `diff` subcommand that shows the unified diff of the context files.
+func NewDiffCmd() *cobra.Command {
+	d := &diffCmd{
+		logger: slog.Default().WithGroup("diff"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "diff [<env> [<env>]]",
+		Short: "Show the changes between the context files of environments and provider locations",
+		Long: `Show the changes between the context files of environments and provider locations.
+
+With no argument, compare the active environment with the files installed in the provider location,
+which are the files the provider CLI actually reads.
+With one argument, compare the named environment with the installed files.
+With two arguments, compare the first environment with the second one.
+
+If --provider is not given, every provider that has the environments is compared.`,
+		Args: cobra.MaximumNArgs(2),
+	}
+	cmd.RunE = d.RunDiff
+	cmd.Annotations = readOnly()
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&d.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.BoolVar(&d.exitCode, "exit-code", false, "exit with non-zero status if there are differences")
+
+	return cmd
+}
+
+// RunDiff runs the `diff` subcommand which prints the unified diff of the context files.
+func (c *diffCmd) RunDiff(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunDiff",
+		slog.String("provider", c.provider.String()),
+		slog.Any("args", args),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	var compared int
+	var differ bool
+	for _, provider := range providers {
+		diff, err := c.diff(provider, args)
+		if err != nil {
+			// Skip providers which do not have the environments unless the provider was given explicitly
+			if c.provider == "" && len(args) > 0 && errors.Is(err, contextmanager.ErrEnvironmentNotExist) {
+				continue
+			}
+			return fmt.Errorf("diff %s: %w", provider, err)
+		}
+		compared++
+		if diff != "" {
+			differ = true
+			io.WriteString(w, diff)
+		}
+	}
+	if compared == 0 {
+		return fmt.Errorf("environment %s: %w", args[len(args)-1], contextmanager.ErrEnvironmentNotExist)
+	}
+
+	if c.exitCode && differ {
+		// Like git, the diff has been printed and only the exit code reports it
+		cmd.SilenceUsage = true
+		return &ExitError{Code: 1}
+	}
+
+	return nil
+}
+
+// diff returns the unified diff of the context files of the provider selected by args.
+func (c *diffCmd) diff(provider contextmanager.Provider, args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		env, err := contextmanager.ResolveEnvironment(provider)
+		if err != nil {
+			return "", err
+		}
+		return contextmanager.DiffInstalled(env)
+
+	case 1:
+		env, err := contextmanager.LookupEnvironment(provider, args[0])
+		if err != nil {
+			return "", err
+		}
+		return contextmanager.DiffInstalled(env)
+
+	default:
+		a, err := contextmanager.LookupEnvironment(provider, args[0])
+		if err != nil {
+			return "", err
+		}
+		b, err := contextmanager.LookupEnvironment(provider, args[1])
+		if err != nil {
+			return "", err
+		}
+		return contextmanager.DiffEnvironments(a, b)
+	}
+}
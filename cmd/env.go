@@ -0,0 +1,138 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type envCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	shell    string
+	unset    bool
+}
+
+// NewEnvCmd returns the `env` subcommand that redirects the provider CLIs to the homes of the environments.
+func NewEnvCmd() *cobra.Command {
+	e := &envCmd{
+		logger: slog.Default().WithGroup("env"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "env [<name>]",
+		Short: "Redirect the provider CLIs to the homes of the context environments",
+		Long: `Redirect the provider CLIs to the homes of the context environments.
+
+Materialize a provider home for the environment in the llmctxenv root, and print the shell code setting the
+variable which points the provider CLI at it, such as CLAUDE_CONFIG_DIR or CODEX_HOME:
+
+  eval "$(llmctxenv env -p claude review)"
+
+The home links the context files of the environment and every other entry of the real provider home, so that
+the settings and credentials are shared while the files in the real home are never changed. As each
+environment has its own home, CLIs with different environments can run at the same time.
+
+With no argument, the active environment of the current directory is used. If --provider is not given, the
+providers whose CLI supports the redirection are redirected.`,
+		Args: cobra.MaximumNArgs(1),
+	}
+	cmd.RunE = e.RunEnv
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&e.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.StringVar(&e.shell, "shell", "", "shell to print the code for: "+strings.Join(shells, ", ")+" (default $SHELL)")
+	f.BoolVar(&e.unset, "unset", false, "print the code removing the redirection")
+
+	return cmd
+}
+
+// RunEnv runs the `env` subcommand which materializes the homes of the environments and prints the shell
+// code redirecting the provider CLIs to them.
+func (c *envCmd) RunEnv(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunEnv",
+		slog.Any("args", args),
+		slog.String("provider", c.provider.String()),
+		slog.String("shell", c.shell),
+		slog.Bool("unset", c.unset),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+	sh, err := detectShell(c.shell)
+	if err != nil {
+		return err
+	}
+	if c.unset && len(args) > 0 {
+		return errors.New("--unset does not take an environment name")
+	}
+
+	w := cmd.OutOrStdout()
+	var redirected int
+	for _, provider := range providers {
+		key, err := contextmanager.HomeVariable(provider)
+		if err != nil {
+			// Skip providers which cannot be redirected unless the provider was given explicitly
+			if c.provider == "" && errors.Is(err, contextmanager.ErrRedirectUnsupported) {
+				continue
+			}
+			return err
+		}
+		if c.unset {
+			unsetVar(w, sh, key)
+			continue
+		}
+
+		var env *contextmanager.Environment
+		if len(args) > 0 {
+			env, err = contextmanager.LookupEnvironment(provider, args[0])
+			if err != nil {
+				// Skip providers which do not have the environment unless the provider was given explicitly
+				if c.provider == "" && errors.Is(err, contextmanager.ErrEnvironmentNotExist) {
+					continue
+				}
+				return err
+			}
+		} else {
+			env, err = contextmanager.ResolveEnvironment(provider)
+			if err != nil {
+				return fmt.Errorf("resolve %s environment: %w", provider, err)
+			}
+		}
+
+		dir, err := contextmanager.MaterializeHome(env)
+		if err != nil {
+			return fmt.Errorf("materialize %s environment %s home: %w", provider, env.Name, err)
+		}
+		exportVar(w, sh, key, dir)
+		redirected++
+	}
+	if len(args) > 0 && redirected == 0 {
+		return fmt.Errorf("environment %s: %w", args[0], contextmanager.ErrEnvironmentNotExist)
+	}
+
+	return nil
+}
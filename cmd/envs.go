@@ -0,0 +1,113 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type envsCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+}
+
+// NewEnvsCmd returns the `envs` subcommand that lists named context environments.
+func NewEnvsCmd() *cobra.Command {
+	e := &envsCmd{
+		logger: slog.Default().WithGroup("envs"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "envs",
+		Short: "List named context environments",
+		Args:  cobra.NoArgs,
+	}
+	cmd.RunE = e.RunEnvs
+	cmd.Annotations = readOnly()
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&e.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+
+	return cmd
+}
+
+// RunEnvs runs the `envs` subcommand which lists the environments of each provider.
+//
+// The active environment is marked with an asterisk. A selected environment which no longer exists is
+// listed as missing.
+func (c *envsCmd) RunEnvs(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunEnvs",
+		slog.String("provider", c.provider.String()),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current directory: %w", err)
+	}
+
+	w := cmd.OutOrStdout()
+	for _, provider := range providers {
+		names, err := contextmanager.Environments(provider)
+		if err != nil {
+			return fmt.Errorf("list %s environments: %w", provider, err)
+		}
+		// Resolve the selected name only, so that a selection of a removed environment is still listed
+		sel, err := contextmanager.SelectEnvironment(provider, dir)
+		if err != nil {
+			return fmt.Errorf("resolve %s environment: %w", provider, err)
+		}
+		active := sel.Name
+		if active == contextmanager.ProjectEnvironment {
+			names = slices.Insert(names, 1, active)
+		}
+
+		if len(providers) > 1 {
+			fmt.Fprintf(w, "%s:\n", provider)
+		}
+		for _, name := range names {
+			mark := " "
+			if name == active {
+				mark = "*"
+			}
+			fmt.Fprintf(w, "%s %s\n", mark, name)
+		}
+		if !slices.Contains(names, active) {
+			hint := "use"
+			switch sel.Source() {
+			case contextmanager.SourceEnv:
+				hint = "shell"
+			case contextmanager.SourceLocal, contextmanager.SourceBranchRule:
+				hint = "local"
+			}
+			fmt.Fprintf(w, "* %s (missing, select another environment with `%s`)\n", active, hint)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type execCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	env      string
+	strategy contextmanager.Strategy
+}
+
+// NewExecCmd returns the `exec` subcommand that runs a command with a temporarily activated environment.
+func NewExecCmd() *cobra.Command {
+	e := &execCmd{
+		logger: slog.Default().WithGroup("exec"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "exec --env <name> [--] <command> [<args>...]",
+		Short: "Run a command with a temporarily activated context environment",
+		Long: `Run a command with a temporarily activated context environment.
+
+The environment given by --env is activated for the provider given by --provider, or for every provider that
+has it, and the command runs with the standard input and outputs of llmctxenv:
+
+  llmctxenv exec --env review -- claude
+
+SIGINT and SIGTERM are forwarded to the command. When the command exits, whether it succeeds or not, the
+previous environments are activated again, or the providers are deactivated if they had none. The installed
+files the command changed, such as a copy edited in place, are saved to the backup directory first and
+reported, as deactivate --force does. The exit code of the command is the exit code of llmctxenv.
+
+The command also gets the ` + contextmanager.EnvironmentVariable("<provider>") + ` variable of each activated provider, so that the
+shell hook of a nested shell keeps the environment.`,
+		Args: cobra.MinimumNArgs(1),
+	}
+	cmd.RunE = e.RunExec
+
+	f := cmd.Flags()
+	// The flags following the command belong to it
+	f.SetInterspersed(false)
+	f.StringVarP((*string)(&e.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.StringVarP(&e.env, "env", "e", "", "name of the environment to activate while the command runs")
+	f.StringVarP((*string)(&e.strategy), "strategy", "s", "", "activation strategy: symlink, hardlink or copy (default from config)")
+	cmd.MarkFlagRequired("env")
+
+	return cmd
+}
+
+// RunExec runs the `exec` subcommand which activates the environment, runs the command and restores the
+// previous environments.
+func (c *execCmd) RunExec(cmd *cobra.Command, args []string) (err error) {
+	c.logger.DebugContext(cmd.Context(), "RunExec",
+		slog.Any("args", args),
+		slog.String("provider", c.provider.String()),
+		slog.String("env", c.env),
+		slog.String("strategy", c.strategy.String()),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+	if c.strategy != "" {
+		if _, err := contextmanager.ParseStrategy(c.strategy.String()); err != nil {
+			return err
+		}
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+
+	var envs []*contextmanager.Environment
+	for _, provider := range providers {
+		env, err := contextmanager.LookupEnvironment(provider, c.env)
+		if err != nil {
+			// Skip providers which do not have the environment unless the provider was given explicitly
+			if c.provider == "" && errors.Is(err, contextmanager.ErrEnvironmentNotExist) {
+				continue
+			}
+			return err
+		}
+		envs = append(envs, env)
+	}
+	if len(envs) == 0 {
+		return fmt.Errorf("environment %s: %w", c.env, contextmanager.ErrEnvironmentNotExist)
+	}
+
+	cfg, err := contextmanager.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	// Restore in the reverse order of the activation whatever happens from here
+	var restores []func() (map[string]*contextmanager.Backup, error)
+	defer func() {
+		var errs []error
+		for _, restore := range slices.Backward(restores) {
+			saved, err := restore()
+			for target, b := range saved {
+				fmt.Fprintf(cmd.ErrOrStderr(), "llmctxenv: saved modified %s to %s\n", target, b.Path())
+			}
+			errs = append(errs, err)
+		}
+		if rerr := errors.Join(errs...); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+	}()
+
+	environ := os.Environ()
+	for _, env := range envs {
+		strategy := cmp.Or(c.strategy, cfg.StrategyFor(env.Provider))
+		restore, err := contextmanager.ActivateTemporarily(env, strategy)
+		if err != nil {
+			return fmt.Errorf("activate %s environment %s: %w", env.Provider, env.Name, err)
+		}
+		restores = append(restores, restore)
+		environ = append(environ, contextmanager.EnvironmentVariable(env.Provider)+"="+env.Name)
+
+		c.logger.DebugContext(cmd.Context(), "activated",
+			slog.String("provider", env.Provider.String()),
+			slog.String("environment", env.Name),
+			slog.String("strategy", strategy.String()),
+		)
+	}
+
+	// The command is not bound to the context of the root command, which is canceled by the signals. They are
+	// forwarded instead, so that the command decides how to exit and the environments are restored after it.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, unix.SIGINT, unix.SIGTERM)
+	defer signal.Stop(sigs)
+
+	child := exec.Command(path, args[1:]...)
+	child.Args[0] = args[0]
+	child.Env = environ
+	child.Stdin = cmd.InOrStdin()
+	child.Stdout = cmd.OutOrStdout()
+	child.Stderr = cmd.ErrOrStderr()
+	if err := child.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				child.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if err := child.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+		code := exitErr.ExitCode()
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			// Same as the shells for the commands terminated by a signal
+			code = 128 + int(ws.Signal())
+		}
+		cmd.SilenceUsage = true
+		return &ExitError{Code: code}
+	}
+
+	return nil
+}
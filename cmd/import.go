@@ -0,0 +1,140 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type importCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	name     string
+	activate bool
+	strategy contextmanager.Strategy
+}
+
+// NewImportCmd returns the `import` subcommand that creates an environment from the existing context files
+// in the provider locations.
+func NewImportCmd() *cobra.Command {
+	i := &importCmd{
+		logger: slog.Default().WithGroup("import"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "import --as <name>",
+		Short: "Create a context environment from the existing files in the provider locations",
+		Long: `Create a context environment from the existing files in the provider locations.
+
+The context files the provider CLIs currently read are copied into the new environment. If --provider is
+not given, every provider that has a context file is imported.
+
+With --activate, the imported environment is also selected as the global environment and activated, which
+replaces the original files with managed deployments. The originals are kept in the backup directory and
+restored by deactivate.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.RunE = i.RunImport
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&i.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.StringVar(&i.name, "as", "", "name of the environment to create")
+	f.BoolVar(&i.activate, "activate", false, "select and activate the imported environment")
+	f.StringVarP((*string)(&i.strategy), "strategy", "s", "", "activation strategy: symlink, hardlink or copy (default from config)")
+	cmd.MarkFlagRequired("as")
+
+	return cmd
+}
+
+// RunImport runs the `import` subcommand which copies the context files of the provider locations into
+// a new environment.
+func (c *importCmd) RunImport(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunImport",
+		slog.String("provider", c.provider.String()),
+		slog.String("as", c.name),
+		slog.Bool("activate", c.activate),
+		slog.String("strategy", c.strategy.String()),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+	if err := contextmanager.ValidateEnvironmentName(c.name); err != nil {
+		return err
+	}
+	if c.strategy != "" {
+		if _, err := contextmanager.ParseStrategy(c.strategy.String()); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := contextmanager.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	w := cmd.OutOrStdout()
+	var imported int
+	for _, provider := range providers {
+		env, err := contextmanager.Import(provider, c.name)
+		if err != nil {
+			// Skip providers which have no context file unless the provider was given explicitly
+			if c.provider == "" && errors.Is(err, contextmanager.ErrNothingToImport) {
+				continue
+			}
+			return fmt.Errorf("import %s environment %s: %w", provider, c.name, err)
+		}
+		imported++
+
+		files, err := env.Files()
+		if err != nil {
+			return err
+		}
+		for _, name := range files {
+			fmt.Fprintf(w, "%s: imported %s into %s\n", provider, name, filepath.Join(env.Dir, name))
+		}
+
+		if !c.activate {
+			continue
+		}
+		strategy := cmp.Or(c.strategy, cfg.StrategyFor(provider))
+		d, err := contextmanager.Activate(env, strategy)
+		if err != nil {
+			return fmt.Errorf("activate %s environment %s: %w", provider, env.Name, err)
+		}
+		if err := contextmanager.SetGlobalEnvironment(provider, env.Name); err != nil {
+			return fmt.Errorf("use %s environment %s: %w", provider, env.Name, err)
+		}
+		for _, f := range d.Files {
+			fmt.Fprintf(w, "%s: %s -> %s (%s)\n", provider, env.Name, f.Target, d.Strategy)
+		}
+	}
+	if imported == 0 {
+		return contextmanager.ErrNothingToImport
+	}
+
+	return nil
+}
@@ -45,6 +45,7 @@ func NewListCmd() *cobra.Command {
 		Short: "List managed system context files",
 	}
 	cmd.RunE = l.RunList
+	cmd.Annotations = readOnly()
 
 	f := cmd.Flags()
 	f.StringVarP((*string)(&l.provider), "provider", "p", "", "manages system context provider name")
@@ -56,18 +57,23 @@ func NewListCmd() *cobra.Command {
 //
 // TODO(zchee): fix documentations.
 func (c *listCmd) RunList(cmd *cobra.Command, args []string) error {
-	globalDir := contextmanager.GlobalDir(c.provider)
+	if err := requireProvider(c.provider); err != nil {
+		return err
+	}
+
+	env, err := contextmanager.ResolveEnvironment(c.provider)
+	if err != nil {
+		return fmt.Errorf("resolve %s environment: %w", c.provider, err)
+	}
+	globalDir := env.Dir
 
 	c.logger.DebugContext(cmd.Context(), "RunList",
 		slog.Any("args", args),
+		slog.String("environment", env.Name),
 		slog.String("global_directory", globalDir),
 		slog.String("provider", c.provider.String()),
 	)
 
-	if c.provider == "" {
-		return fmt.Errorf("--provider flag must be not empty")
-	}
-
 	if !fileio.IsExist(globalDir) {
 		// Create instructionsDir if not exist
 		if err := os.MkdirAll(globalDir, 0o700); err != nil {
@@ -0,0 +1,193 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type localCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	unset    bool
+	init     bool
+}
+
+// NewLocalCmd returns the `local` subcommand that selects the context environment of the current directory.
+func NewLocalCmd() *cobra.Command {
+	l := &localCmd{
+		logger: slog.Default().WithGroup("local"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "local [<name>]",
+		Short: "Select the context environment of the current directory",
+		Long: `Select the context environment of the current directory.
+
+The environment is written to the ` + contextmanager.LocalVersionFileName + ` file of the current directory, which
+selects the environment in the directory and its subdirectories in preference to the global environment.
+If --provider is not given, the environment is selected for every provider that has it.
+
+The file may also select environments by the checked out git branch with sections such as
+
+  [branch "release/*"]
+  claude=release
+
+which take precedence over the other lines of the file while the branch matches the pattern.
+
+With no argument, show the environments selected by the ` + contextmanager.LocalVersionFileName + ` files of the
+current directory and its parents.
+
+Each project also has a local context directory per provider, which --init creates and prints. When no
+` + contextmanager.LocalVersionFileName + ` file in the project selects an environment and the local context directory
+has context files, the implicit "` + contextmanager.ProjectEnvironment + `" environment backed by it is selected in the
+project and its subdirectories. It can also be selected explicitly by name.`,
+		Args: cobra.MaximumNArgs(1),
+	}
+	cmd.RunE = l.RunLocal
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&l.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.BoolVar(&l.unset, "unset", false, "remove the local environment of the current directory")
+	f.BoolVar(&l.init, "init", false, "create the local context directory of the current project")
+
+	return cmd
+}
+
+// RunLocal runs the `local` subcommand which selects the local environment of the providers.
+func (c *localCmd) RunLocal(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunLocal",
+		slog.Any("args", args),
+		slog.String("provider", c.provider.String()),
+		slog.Bool("unset", c.unset),
+		slog.Bool("init", c.init),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current directory: %w", err)
+	}
+
+	switch {
+	case c.unset && c.init:
+		return errors.New("--unset and --init cannot be used together")
+	case c.unset:
+		if len(args) > 0 {
+			return errors.New("--unset does not take an environment name")
+		}
+		return c.unsetLocal(cmd, providers, dir)
+	case c.init:
+		if len(args) > 0 {
+			return errors.New("--init does not take an environment name")
+		}
+		return c.initLocal(cmd, providers, dir)
+	case len(args) == 0:
+		return c.showLocal(cmd, providers, dir)
+	}
+
+	name := args[0]
+	w := cmd.OutOrStdout()
+	var used int
+	for _, provider := range providers {
+		if err := contextmanager.SetLocalEnvironment(provider, dir, name); err != nil {
+			// Skip providers which do not have the environment unless the provider was given explicitly
+			if c.provider == "" && errors.Is(err, contextmanager.ErrEnvironmentNotExist) {
+				continue
+			}
+			return fmt.Errorf("use %s environment %s locally: %w", provider, name, err)
+		}
+		used++
+		fmt.Fprintf(w, "%s: using %s environment in %s\n", provider, name, dir)
+	}
+	if used == 0 {
+		return fmt.Errorf("environment %s: %w", name, contextmanager.ErrEnvironmentNotExist)
+	}
+
+	return nil
+}
+
+// showLocal prints the local environments of the providers in dir.
+func (c *localCmd) showLocal(cmd *cobra.Command, providers []contextmanager.Provider, dir string) error {
+	w := cmd.OutOrStdout()
+	for _, provider := range providers {
+		sel, err := contextmanager.SelectEnvironment(provider, dir)
+		if err != nil {
+			return fmt.Errorf("resolve %s environment: %w", provider, err)
+		}
+		if sel.IsLocal() || sel.Source() == contextmanager.SourceProject {
+			fmt.Fprintf(w, "%s: %s (%s)\n", provider, sel.Name, setBy(sel))
+		}
+	}
+	return nil
+}
+
+// setBy describes what selects the environment of sel.
+func setBy(sel *contextmanager.Selection) string {
+	switch sel.Source() {
+	case contextmanager.SourceEnv:
+		return "set by " + sel.EnvVar
+	case contextmanager.SourceBranchRule:
+		return fmt.Sprintf("set by branch rule %q of %s on %s", sel.BranchRule, sel.Origin, sel.Branch)
+	case contextmanager.SourceDefault:
+		return "set by default"
+	case contextmanager.SourceProject:
+		return "set by the local context " + sel.Dir
+	}
+	return "set by " + sel.Origin
+}
+
+// initLocal creates the local context directories of the providers in the project of dir.
+func (c *localCmd) initLocal(cmd *cobra.Command, providers []contextmanager.Provider, dir string) error {
+	w := cmd.OutOrStdout()
+	for _, provider := range providers {
+		path, err := contextmanager.LocalDir(provider, dir)
+		if err != nil {
+			return fmt.Errorf("%s local context directory: %w", provider, err)
+		}
+		if err := os.MkdirAll(path, 0o700); err != nil {
+			return fmt.Errorf("mkdir all %s path: %w", path, err)
+		}
+		fmt.Fprintf(w, "%s: %s\n", provider, path)
+	}
+	return nil
+}
+
+// unsetLocal removes the local environments of the providers from the version file in dir.
+func (c *localCmd) unsetLocal(cmd *cobra.Command, providers []contextmanager.Provider, dir string) error {
+	w := cmd.OutOrStdout()
+	for _, provider := range providers {
+		ok, err := contextmanager.UnsetLocalEnvironment(provider, dir)
+		if err != nil {
+			return fmt.Errorf("unset %s local environment: %w", provider, err)
+		}
+		if ok {
+			fmt.Fprintf(w, "%s: removed local environment of %s\n", provider, dir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type materializeCmd struct {
+	logger    *slog.Logger
+	providers []string
+	env       string
+	into      string
+}
+
+// NewMaterializeCmd returns the `materialize` subcommand that writes an environment into a directory laid out as
+// a home directory.
+func NewMaterializeCmd() *cobra.Command {
+	m := &materializeCmd{
+		logger: slog.Default().WithGroup("materialize"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "materialize --env <name> --into <dir>",
+		Short: "Write a context environment into a directory laid out as a home directory",
+		Long: `Write a context environment into a directory laid out as a home directory.
+
+The files of the environment are copied where each provider CLI reads them relative to the home directory,
+such as <dir>/.claude/CLAUDE.md or <dir>/.codex/AGENTS.md, to bake them into container images:
+
+  llmctxenv materialize --env ci --into ./home -p claude -p codex
+
+The output is deterministic so that the image layers are cached: the modes are normalized and the
+modification times are set to SOURCE_DATE_EPOCH, or the Unix epoch. Other files in the directory are kept.
+
+If --provider is not given, the environment is written for every provider that has it.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.RunE = m.RunMaterialize
+
+	f := cmd.Flags()
+	f.StringSliceVarP(&m.providers, "provider", "p", nil, "manages system context provider name, can be repeated (default all providers)")
+	f.StringVarP(&m.env, "env", "e", "", "name of the environment to write")
+	f.StringVar(&m.into, "into", "", "directory to write the environment into")
+	cmd.MarkFlagRequired("env")
+	cmd.MarkFlagRequired("into")
+
+	return cmd
+}
+
+// RunMaterialize runs the `materialize` subcommand which writes the environment of the providers into the
+// directory.
+func (c *materializeCmd) RunMaterialize(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunMaterialize",
+		slog.Any("providers", c.providers),
+		slog.String("env", c.env),
+		slog.String("into", c.into),
+	)
+
+	var providers []contextmanager.Provider
+	for _, name := range c.providers {
+		provider, err := contextmanager.ParseProvider(name)
+		if err != nil {
+			return err
+		}
+		providers = append(providers, provider)
+	}
+	if len(providers) == 0 {
+		providers = contextmanager.Providers()
+	}
+
+	w := cmd.OutOrStdout()
+	var written int
+	for _, provider := range providers {
+		env, err := contextmanager.LookupEnvironment(provider, c.env)
+		if err != nil {
+			// Skip providers which do not have the environment unless the provider was given explicitly
+			if len(c.providers) == 0 && errors.Is(err, contextmanager.ErrEnvironmentNotExist) {
+				continue
+			}
+			return err
+		}
+
+		files, err := contextmanager.Materialize(env, c.into)
+		if err != nil {
+			return fmt.Errorf("materialize %s environment %s: %w", provider, env.Name, err)
+		}
+		written++
+		for _, file := range files {
+			fmt.Fprintf(w, "%s: %s -> %s\n", provider, env.Name, file)
+		}
+	}
+	if written == 0 {
+		return fmt.Errorf("environment %s: %w", c.env, contextmanager.ErrEnvironmentNotExist)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type projectsCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	json     bool
+}
+
+// NewProjectsCmd returns the `projects` subcommand that lists the projects which have a local context.
+func NewProjectsCmd() *cobra.Command {
+	p := &projectsCmd{
+		logger: slog.Default().WithGroup("projects"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "projects",
+		Short: "List the projects which have a local context",
+		Long: `List the projects which have a local context.
+
+Each project is listed with its providers, the environment in use in the project directory and the
+last modification time of its local context. A project whose directory no longer exists is marked as
+missing. A project identified by the remote URL of its git repository is listed by the normalized URL.
+
+The projects cannot be listed while the local contexts use the legacy layout, until they are migrated
+by prune or any command which changes the state, such as activate.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.RunE = p.RunProjects
+	cmd.Annotations = readOnly()
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&p.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.BoolVar(&p.json, "json", false, "print the projects in JSON")
+
+	return cmd
+}
+
+// RunProjects runs the `projects` subcommand which lists the projects which have a local context.
+func (c *projectsCmd) RunProjects(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunProjects",
+		slog.String("provider", c.provider.String()),
+		slog.Bool("json", c.json),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+
+	projects, err := contextmanager.Projects()
+	if err != nil {
+		return fmt.Errorf("list projects: %w", err)
+	}
+	projects = slices.DeleteFunc(projects, func(p *contextmanager.Project) bool {
+		p.Providers = slices.DeleteFunc(p.Providers, func(pp *contextmanager.ProjectProvider) bool {
+			return !slices.Contains(providers, pp.Provider)
+		})
+		return len(p.Providers) == 0
+	})
+
+	w := cmd.OutOrStdout()
+	if c.json {
+		if projects == nil {
+			projects = []*contextmanager.Project{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(projects)
+	}
+
+	for _, p := range projects {
+		switch {
+		case p.Remote != "":
+			fmt.Fprintf(w, "%s (remote)\n", p.Remote)
+		case !p.Exists:
+			fmt.Fprintf(w, "%s (missing)\n", p.Path)
+		default:
+			fmt.Fprintf(w, "%s\n", p.Path)
+		}
+		for _, pp := range p.Providers {
+			env := pp.Environment
+			if env == "" {
+				env = "-"
+			}
+			fmt.Fprintf(w, "  %s: %s (modified %s)\n", pp.Provider, env, pp.Modified.Local().Format(time.DateTime))
+		}
+	}
+
+	return nil
+}
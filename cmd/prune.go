@@ -0,0 +1,95 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type pruneCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	dryRun   bool
+}
+
+// NewPruneCmd returns the `prune` subcommand that removes the local contexts of deleted projects.
+func NewPruneCmd() *cobra.Command {
+	p := &pruneCmd{
+		logger: slog.Default().WithGroup("prune"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the local contexts of projects which no longer exist",
+		Long: `Remove the local contexts of projects which no longer exist.
+
+The removed directories are moved to a trash directory in the llmctxenv root, named by the time of
+the prune, from which they can be recovered. See projects for the projects which have a local context.
+
+The local contexts of the legacy layout are migrated first, unless --dry-run is given.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.RunE = p.RunPrune
+	cmd.Annotations = readOnly() // migrates by itself unless it is a dry run
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&p.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.BoolVarP(&p.dryRun, "dry-run", "n", false, "only report what would be removed")
+
+	return cmd
+}
+
+// RunPrune runs the `prune` subcommand which moves the local contexts of deleted projects to the trash.
+func (c *pruneCmd) RunPrune(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunPrune",
+		slog.String("provider", c.provider.String()),
+		slog.Bool("dry_run", c.dryRun),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+	// A dry run changes nothing, so the local contexts of the legacy layout are migrated only by a prune
+	if !c.dryRun {
+		if _, err := contextmanager.MigrateLocalDirs(); err != nil {
+			return fmt.Errorf("migrate local directories: %w", err)
+		}
+	}
+
+	w := cmd.OutOrStdout()
+	for _, provider := range providers {
+		pruned, err := contextmanager.Prune(provider, c.dryRun)
+		for _, d := range pruned {
+			if c.dryRun {
+				fmt.Fprintf(w, "%s: would remove %s (%s)\n", provider, d.Dir, d.Project)
+				continue
+			}
+			fmt.Fprintf(w, "%s: removed %s (%s) to %s\n", provider, d.Dir, d.Project, d.Trash)
+		}
+		if err != nil {
+			return fmt.Errorf("prune %s: %w", provider, err)
+		}
+	}
+
+	return nil
+}
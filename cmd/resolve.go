@@ -0,0 +1,91 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type resolveCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+}
+
+// NewResolveCmd returns the `resolve` subcommand that accepts the resolved conflicts of `sync`.
+func NewResolveCmd() *cobra.Command {
+	r := &resolveCmd{
+		logger: slog.Default().WithGroup("resolve"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "resolve",
+		Short: "Install the managed files whose sync conflicts have been resolved",
+		Long: `Install the managed files whose sync conflicts have been resolved.
+
+Remove the conflict markers written by sync from the managed files, then run resolve to install them
+into the provider locations. Changes the provider CLI made to the installed files in the meantime are
+merged again.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.RunE = r.RunResolve
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&r.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+
+	return cmd
+}
+
+// RunResolve runs the `resolve` subcommand which installs the resolved managed files.
+func (c *resolveCmd) RunResolve(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunResolve",
+		slog.String("provider", c.provider.String()),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	w := cmd.OutOrStdout()
+	for _, provider := range providers {
+		files, err := contextmanager.Resolve(provider)
+		for _, f := range files {
+			if f.Conflicts > 0 {
+				fmt.Fprintf(w, "%s: conflict in %s, the installed file has been changed again\n", provider, f.Source)
+				errs = append(errs, fmt.Errorf("resolve %s: %d conflicts in %s: %w", provider, f.Conflicts, f.Source, contextmanager.ErrSyncConflict))
+				continue
+			}
+			fmt.Fprintf(w, "%s: resolved %s -> %s\n", provider, f.Source, f.Target)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolve %s: %w", provider, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		// The conflicts have been reported above
+		cmd.SilenceUsage = true
+	}
+	return errors.Join(errs...)
+}
@@ -19,14 +19,37 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/sys/unix"
+
+	"github.com/zchee/llmctxenv/contextmanager"
 )
 
+// ExitError reports the exit code of a command which has reported its result by itself, such as the command
+// run by `exec` or `diff --exit-code`, so that it is not reported as an error again.
+type ExitError struct {
+	Code int
+}
+
+// Error implements error.
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// annotationReadOnly is the annotation of the commands which only read the state, see [readOnly].
+const annotationReadOnly = "llmctxenv_read_only"
+
+// readOnly returns the annotations of a command which only reads the state, which does not migrate the
+// local contexts of the legacy layout before it runs.
+func readOnly() map[string]string {
+	return map[string]string{annotationReadOnly: "true"}
+}
+
 type llmCLIEnvCmd struct {
 	cmd         *cobra.Command
 	leveler     *slog.LevelVar
@@ -56,12 +79,18 @@ func New() *llmCLIEnvCmd {
 		Version: "v0.0.0",
 		Short:   "Manages the LLM CLIs context environment.",
 		Args:    cobra.MaximumNArgs(1),
+		// The error returned from Execute is reported by the main function
+		SilenceErrors: true,
 	}
-	// Handle "--verbose" flag
-	cmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+	// Handle "--verbose" flag, and migrate the local contexts once before a command changes the state
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		if llmCLIEnv.verbose {
 			llmCLIEnv.leveler.Set(slog.LevelDebug)
 		}
+		if cmd.Annotations[annotationReadOnly] != "" {
+			return nil
+		}
+		return llmCLIEnv.migrateLocalDirs(cmd)
 	}
 
 	// Set version flag to only root command
@@ -72,7 +101,29 @@ func New() *llmCLIEnvCmd {
 	fs := cmd.PersistentFlags()
 	fs.BoolVar(&llmCLIEnv.verbose, "verbose", false, "Set verbose mode")
 
-	cmd.AddCommand(NewListCmd())
+	cmd.AddCommand(
+		NewListCmd(),
+		NewCreateCmd(),
+		NewEnvsCmd(),
+		NewUseCmd(),
+		NewLocalCmd(),
+		NewShellCmd(),
+		NewEnvCmd(),
+		NewShellInitCmd(),
+		NewHookCmd(),
+		NewWhichCmd(),
+		NewActivateCmd(),
+		NewDeactivateCmd(),
+		NewExecCmd(),
+		NewMaterializeCmd(),
+		NewStatusCmd(),
+		NewDiffCmd(),
+		NewImportCmd(),
+		NewSyncCmd(),
+		NewResolveCmd(),
+		NewProjectsCmd(),
+		NewPruneCmd(),
+	)
 
 	llmCLIEnv.cmd = cmd
 
@@ -86,3 +137,44 @@ func (c *llmCLIEnvCmd) Execute() error {
 
 	return c.cmd.ExecuteContext(ctx)
 }
+
+// migrateLocalDirs migrates the local contexts of the legacy layout, see [contextmanager.MigrateLocalDirs].
+func (c *llmCLIEnvCmd) migrateLocalDirs(cmd *cobra.Command) error {
+	migrations, err := contextmanager.MigrateLocalDirs()
+	for _, m := range migrations {
+		c.logger.InfoContext(cmd.Context(), "migrated local context",
+			slog.String("provider", m.Provider.String()),
+			slog.String("from", m.From),
+			slog.String("to", m.To),
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("migrate local directories: %w", err)
+	}
+	return nil
+}
+
+// selectProviders returns the providers selected by the --provider flag value.
+//
+// It returns every known provider if provider is empty.
+func selectProviders(provider contextmanager.Provider) ([]contextmanager.Provider, error) {
+	if provider == "" {
+		return contextmanager.Providers(), nil
+	}
+
+	p, err := contextmanager.ParseProvider(provider.String())
+	if err != nil {
+		return nil, err
+	}
+	return []contextmanager.Provider{p}, nil
+}
+
+// requireProvider validates the --provider flag value which must be not empty.
+func requireProvider(provider contextmanager.Provider) error {
+	if provider == "" {
+		return fmt.Errorf("--provider flag must be not empty")
+	}
+
+	_, err := contextmanager.ParseProvider(provider.String())
+	return err
+}
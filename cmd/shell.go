@@ -0,0 +1,188 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+// shells are the shells whose code is printed by `shell` and `shell-init`.
+var shells = []string{"bash", "zsh", "fish"}
+
+// detectShell returns the shell given by the --shell flag or the SHELL environment variable.
+func detectShell(flag string) (string, error) {
+	sh := flag
+	if sh == "" {
+		sh = filepath.Base(os.Getenv("SHELL"))
+	}
+	if !slices.Contains(shells, sh) {
+		if flag == "" {
+			// Any POSIX shell understands the bash code
+			return "bash", nil
+		}
+		return "", fmt.Errorf("unsupported shell %q, want one of %s", sh, strings.Join(shells, ", "))
+	}
+	return sh, nil
+}
+
+// quote returns s quoted as a single word of the shell.
+func quote(sh, s string) string {
+	if sh == "fish" {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+	}
+	return "'" + strings.ReplaceAll(s, `'`, `'\''`) + "'"
+}
+
+// exportVar writes the code setting the environment variable of the shell.
+func exportVar(w io.Writer, sh, key, value string) {
+	if sh == "fish" {
+		fmt.Fprintf(w, "set -gx %s %s;\n", key, quote(sh, value))
+		return
+	}
+	fmt.Fprintf(w, "export %s=%s;\n", key, quote(sh, value))
+}
+
+// unsetVar writes the code removing the environment variable of the shell.
+func unsetVar(w io.Writer, sh, key string) {
+	if sh == "fish" {
+		fmt.Fprintf(w, "set -e %s;\n", key)
+		return
+	}
+	fmt.Fprintf(w, "unset %s;\n", key)
+}
+
+type shellCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	shell    string
+	unset    bool
+}
+
+// NewShellCmd returns the `shell` subcommand that selects the context environment of the current shell.
+func NewShellCmd() *cobra.Command {
+	s := &shellCmd{
+		logger: slog.Default().WithGroup("shell"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "shell [<name>]",
+		Short: "Select the context environment of the current shell",
+		Long: `Select the context environment of the current shell.
+
+Print the shell code setting the ` + contextmanager.EnvEnvironment + ` environment variable, or the ` + contextmanager.EnvironmentVariable("<provider>") + `
+variable of the provider given by --provider, which select the environment in preference to the version files.
+Evaluate the output in the shell, or set up the shell with shell-init to evaluate it automatically:
+
+  eval "$(llmctxenv shell review)"
+
+` + contextmanager.EnvEnvironment + ` applies only to the providers that have the environment, the variable of a provider takes
+precedence over it.
+
+The variables do not isolate the shell: they only decide which environment llmctxenv selects, while the
+provider CLIs read the context files installed in their user-wide location, which every shell shares. The
+environment is installed there by activate or the shell-init hook, so the shell which installs last wins.
+To run CLIs with different environments at the same time, redirect them to the environment homes with env.
+
+With no argument, show the environments selected by the environment variables.`,
+		Args: cobra.MaximumNArgs(1),
+	}
+	cmd.RunE = s.RunShell
+	cmd.Annotations = readOnly()
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&s.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.StringVar(&s.shell, "shell", "", "shell to print the code for: "+strings.Join(shells, ", ")+" (default $SHELL)")
+	f.BoolVar(&s.unset, "unset", false, "print the code removing the environment variables")
+
+	return cmd
+}
+
+// RunShell runs the `shell` subcommand which prints the shell code selecting the environment of the shell.
+func (c *shellCmd) RunShell(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunShell",
+		slog.Any("args", args),
+		slog.String("provider", c.provider.String()),
+		slog.String("shell", c.shell),
+		slog.Bool("unset", c.unset),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+	sh, err := detectShell(c.shell)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	switch {
+	case c.unset:
+		if len(args) > 0 {
+			return errors.New("--unset does not take an environment name")
+		}
+		if c.provider == "" {
+			unsetVar(w, sh, contextmanager.EnvEnvironment)
+		}
+		for _, provider := range providers {
+			unsetVar(w, sh, contextmanager.EnvironmentVariable(provider))
+		}
+		return nil
+
+	case len(args) == 0:
+		for _, provider := range providers {
+			sel, err := contextmanager.SelectEnvironment(provider, ".")
+			if err != nil {
+				return fmt.Errorf("resolve %s environment: %w", provider, err)
+			}
+			if sel.Source() == contextmanager.SourceEnv {
+				fmt.Fprintf(w, "%s: %s (%s)\n", provider, sel.Name, setBy(sel))
+			}
+		}
+		return nil
+	}
+
+	name := args[0]
+	if c.provider != "" {
+		if _, err := contextmanager.LookupEnvironment(c.provider, name); err != nil {
+			return err
+		}
+		exportVar(w, sh, contextmanager.EnvironmentVariable(c.provider), name)
+		return nil
+	}
+
+	if !slices.ContainsFunc(providers, func(provider contextmanager.Provider) bool {
+		_, err := contextmanager.LookupEnvironment(provider, name)
+		return err == nil
+	}) {
+		return fmt.Errorf("environment %s: %w", name, contextmanager.ErrEnvironmentNotExist)
+	}
+	exportVar(w, sh, contextmanager.EnvEnvironment, name)
+
+	return nil
+}
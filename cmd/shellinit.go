@@ -0,0 +1,184 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+// shellInitScripts are the scripts printed by `shell-init`, in which %[1]s is the quoted path of the
+// llmctxenv executable.
+var shellInitScripts = map[string]string{
+	"bash": `_llmctxenv_hook() {
+  local status=$?
+  if [[ "${_llmctxenv_pwd-}" != "$PWD" ]]; then
+    _llmctxenv_pwd=$PWD
+    %[1]s hook
+  fi
+  return $status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_llmctxenv_hook;"* ]]; then
+  PROMPT_COMMAND="_llmctxenv_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+llmctxenv() {
+  if [ "$1" = shell ] && [ "$#" -gt 1 ] && [[ " $* " != *" -h "* && " $* " != *" --help "* ]]; then
+    shift
+    eval "$(%[1]s shell --shell bash "$@")" && %[1]s hook
+  else
+    %[1]s "$@"
+  fi
+}
+`,
+	"zsh": `_llmctxenv_hook() {
+  %[1]s hook
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _llmctxenv_hook
+_llmctxenv_hook
+llmctxenv() {
+  if [[ "$1" == shell && $# -gt 1 && " $* " != *" -h "* && " $* " != *" --help "* ]]; then
+    shift
+    eval "$(%[1]s shell --shell zsh "$@")" && %[1]s hook
+  else
+    %[1]s "$@"
+  fi
+}
+`,
+	"fish": `function _llmctxenv_hook --on-variable PWD
+  %[1]s hook
+end
+_llmctxenv_hook
+function llmctxenv
+  if test "$argv[1]" = shell; and test (count $argv) -gt 1; and not contains -- -h $argv; and not contains -- --help $argv
+    %[1]s shell --shell fish $argv[2..-1] | source; and %[1]s hook
+  else
+    %[1]s $argv
+  end
+end
+`,
+}
+
+type shellInitCmd struct {
+	logger *slog.Logger
+}
+
+// NewShellInitCmd returns the `shell-init` subcommand that prints the shell integration script.
+func NewShellInitCmd() *cobra.Command {
+	s := &shellInitCmd{
+		logger: slog.Default().WithGroup("shell-init"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "shell-init bash|zsh|fish",
+		Short: "Print the shell integration script",
+		Long: `Print the shell integration script, to be evaluated in the shell startup file:
+
+  eval "$(llmctxenv shell-init bash)"   # ~/.bashrc
+  eval "$(llmctxenv shell-init zsh)"    # ~/.zshrc
+  llmctxenv shell-init fish | source    # ~/.config/fish/config.fish
+
+The script makes the shell command evaluate its output, and installs a hook running when the shell starts
+and whenever the current directory changes. The hook activates the environment selected in the current
+directory for each provider whose installed environment differs, so entering a project activates its local
+environment and leaving it restores the global environment. The strategy of the installed environment is
+kept. The shell command also runs the hook after changing the environment variables of the shell.
+
+The hook does not run on every prompt, so a selection changed without leaving the directory, e.g. by local,
+is followed on the next directory change; run activate to apply it at once. The selections are cached by
+directory and version file modification time, so the hook is cheap when nothing changes.`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: shells,
+	}
+	cmd.RunE = s.RunShellInit
+	cmd.Annotations = readOnly()
+
+	return cmd
+}
+
+// RunShellInit runs the `shell-init` subcommand which prints the shell integration script.
+func (c *shellInitCmd) RunShellInit(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunShellInit",
+		slog.Any("args", args),
+	)
+
+	sh, err := detectShell(args[0])
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "llmctxenv"
+	}
+
+	// The "command" prefix runs the executable rather than the llmctxenv function defined by the script
+	fmt.Fprintf(cmd.OutOrStdout(), shellInitScripts[sh], "command "+quote(sh, exe))
+
+	return nil
+}
+
+type hookCmd struct {
+	logger *slog.Logger
+}
+
+// NewHookCmd returns the hidden `hook` subcommand run by the shell integration script on directory changes.
+func NewHookCmd() *cobra.Command {
+	h := &hookCmd{
+		logger: slog.Default().WithGroup("hook"),
+	}
+
+	cmd := &cobra.Command{
+		Use:    "hook",
+		Short:  "Activate the environments selected in the current directory",
+		Args:   cobra.NoArgs,
+		Hidden: true,
+	}
+	cmd.RunE = h.RunHook
+
+	return cmd
+}
+
+// RunHook runs the `hook` subcommand which activates the environments selected in the current directory for
+// the providers whose installed environment differs.
+//
+// The errors are reported without failing, so that a broken environment does not break the shell prompt.
+func (c *hookCmd) RunHook(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunHook")
+
+	dir, err := os.Getwd()
+	if err != nil {
+		// The current directory has been removed
+		return nil
+	}
+
+	activated, err := contextmanager.Follow(dir, contextmanager.Providers())
+	w := cmd.ErrOrStderr()
+	for _, sel := range activated {
+		fmt.Fprintf(w, "llmctxenv: %s: activated %s environment (%s)\n", sel.Provider, sel.Name, setBy(sel))
+	}
+	if err != nil {
+		fmt.Fprintf(w, "llmctxenv: %s\n", strings.ReplaceAll(err.Error(), "\n", "\nllmctxenv: "))
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+// errDrifted is returned by `status --exit-code` when a provider location has drifted from its active environment.
+var errDrifted = errors.New("provider locations have drifted from the active environments")
+
+type statusCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	exitCode bool
+}
+
+// NewStatusCmd returns the `status` subcommand that reports the drift between the managed and installed context files.
+func NewStatusCmd() *cobra.Command {
+	s := &statusCmd{
+		logger: slog.Default().WithGroup("status"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of the installed context files",
+		Long: `Show the active environment and the state of each installed context file of the providers.
+
+The state of a file is one of:
+
+  in-sync         the installed file holds the managed file
+  modified        the installed file has been changed since activation, typically by the provider CLI
+  outdated        the managed file has been changed since activation
+  source-missing  the managed file has been removed from the environment
+  missing         the installed file has been removed
+  replaced        the installed file has been replaced by an unmanaged file
+  conflict        the managed file holds conflict markers written by sync, see resolve
+  not-deployed    the file of the active environment has not been installed`,
+		Args: cobra.NoArgs,
+	}
+	cmd.RunE = s.RunStatus
+	cmd.Annotations = readOnly()
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&s.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.BoolVar(&s.exitCode, "exit-code", false, "exit with non-zero status if any provider location has drifted")
+
+	return cmd
+}
+
+// RunStatus runs the `status` subcommand which reports the state of the installed context files.
+func (c *statusCmd) RunStatus(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunStatus",
+		slog.String("provider", c.provider.String()),
+		slog.Bool("exit_code", c.exitCode),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	var drifted bool
+	for _, provider := range providers {
+		s, err := contextmanager.StatusOf(provider)
+		if err != nil {
+			return fmt.Errorf("status of %s: %w", provider, err)
+		}
+		drifted = drifted || s.Drifted()
+
+		switch {
+		case s.EnvironmentErr != nil:
+			fmt.Fprintf(w, "%s: %s (%v)\n", provider, s.Environment, s.EnvironmentErr)
+		case s.Deployment == nil:
+			fmt.Fprintf(w, "%s: %s (not activated)\n", provider, s.Environment)
+		case s.Switched():
+			active := s.Environment
+			if active == s.Deployment.Environment {
+				// The project environment of another project
+				active += " of " + s.Selection.Dir
+			}
+			fmt.Fprintf(w, "%s: %s (%s, activated %s), active environment is %s\n", provider, s.Deployment.Environment, s.Deployment.Strategy, activated(s.Deployment), active)
+		default:
+			fmt.Fprintf(w, "%s: %s (%s, activated %s)\n", provider, s.Environment, s.Deployment.Strategy, activated(s.Deployment))
+		}
+		if s.Selection != nil && s.Selection.BranchRule != "" {
+			fmt.Fprintf(w, "  %s %s\n", s.Environment, setBy(s.Selection))
+		}
+		for _, f := range s.Files {
+			fmt.Fprintf(w, "  %-14s  %s\n", f.Status, f.Target)
+		}
+	}
+
+	if c.exitCode && drifted {
+		cmd.SilenceUsage = true
+		return errDrifted
+	}
+
+	return nil
+}
+
+// activated returns the local time at which d was activated.
+func activated(d *contextmanager.Deployment) string {
+	return d.Time.Local().Format(time.DateTime)
+}
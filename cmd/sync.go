@@ -0,0 +1,109 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type syncCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+	dryRun   bool
+}
+
+// NewSyncCmd returns the `sync` subcommand that synchronizes the installed context files with the environments.
+func NewSyncCmd() *cobra.Command {
+	s := &syncCmd{
+		logger: slog.Default().WithGroup("sync"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Synchronize the installed context files with the environments in both directions",
+		Long: `Synchronize the installed context files with the environments in both directions.
+
+Each installed file is compared with its managed file and the content recorded at the activation.
+Changes made only to the installed file, e.g. memories added by the provider CLI, are pulled into the
+environment. Changes made only to the managed file are pushed to the provider location. Changes made to
+both are merged line by line.
+
+If the changes conflict, the managed file holds git style conflict markers and the installed file is left
+unchanged. Edit the managed file to remove the markers and run resolve to install it.
+Missing or replaced files are skipped; see status for details.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.RunE = s.RunSync
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&s.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+	f.BoolVarP(&s.dryRun, "dry-run", "n", false, "only report what would be synchronized")
+
+	return cmd
+}
+
+// RunSync runs the `sync` subcommand which pulls the changes of the installed files into the environments
+// and pushes the changes of the environments to the provider locations.
+func (c *syncCmd) RunSync(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunSync",
+		slog.String("provider", c.provider.String()),
+		slog.Bool("dry_run", c.dryRun),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+
+	// A conflict of a provider must not prevent the others from being synchronized
+	var errs []error
+	w := cmd.OutOrStdout()
+	for _, provider := range providers {
+		files, err := contextmanager.Sync(provider, c.dryRun)
+		for _, f := range files {
+			switch f.Action {
+			case contextmanager.SyncUpToDate:
+				c.logger.DebugContext(cmd.Context(), "up-to-date", slog.String("target", f.Target))
+			case contextmanager.SyncPull:
+				fmt.Fprintf(w, "%s: pull %s -> %s\n", provider, f.Target, f.Source)
+			case contextmanager.SyncPush:
+				fmt.Fprintf(w, "%s: push %s -> %s\n", provider, f.Source, f.Target)
+			case contextmanager.SyncMerge:
+				fmt.Fprintf(w, "%s: merge %s -> %s\n", provider, f.Target, f.Source)
+			case contextmanager.SyncConflict:
+				fmt.Fprintf(w, "%s: conflict in %s, edit it and run `llmctxenv resolve -p %s`\n", provider, f.Source, provider)
+			default:
+				fmt.Fprintf(w, "%s: skip %s (%s)\n", provider, f.Target, f.Status)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sync %s: %w", provider, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		// The conflicts have been reported above
+		cmd.SilenceUsage = true
+	}
+	return errors.Join(errs...)
+}
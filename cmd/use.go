@@ -0,0 +1,98 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+type useCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+}
+
+// NewUseCmd returns the `use` subcommand that selects the global context environment.
+func NewUseCmd() *cobra.Command {
+	u := &useCmd{
+		logger: slog.Default().WithGroup("use"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "use <name>",
+		Short: "Select the global context environment",
+		Long: `Select the global context environment.
+
+If --provider is not given, the environment is selected for every provider that has it.
+Use the "global" environment to go back to the default context files.
+A local environment selected with the local subcommand takes precedence over the global environment.`,
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.RunE = u.RunUse
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&u.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+
+	return cmd
+}
+
+// RunUse runs the `use` subcommand which selects the global environment of the providers.
+func (c *useCmd) RunUse(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	c.logger.DebugContext(cmd.Context(), "RunUse",
+		slog.String("name", name),
+		slog.String("provider", c.provider.String()),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	var used int
+	for _, provider := range providers {
+		if err := contextmanager.SetGlobalEnvironment(provider, name); err != nil {
+			// Skip providers which do not have the environment unless the provider was given explicitly
+			if c.provider == "" && errors.Is(err, contextmanager.ErrEnvironmentNotExist) {
+				continue
+			}
+			return fmt.Errorf("use %s environment %s: %w", provider, name, err)
+		}
+		used++
+		fmt.Fprintf(w, "%s: using %s environment\n", provider, name)
+
+		// The global environment has no effect where a shell or local environment is selected
+		if dir, err := os.Getwd(); err == nil {
+			if sel, err := contextmanager.SelectEnvironment(provider, dir); err == nil && sel.Source() != contextmanager.SourceGlobal {
+				fmt.Fprintf(w, "%s: %s environment is %s in this directory\n", provider, sel.Name, setBy(sel))
+			}
+		}
+	}
+	if used == 0 {
+		return fmt.Errorf("environment %s: %w", name, contextmanager.ErrEnvironmentNotExist)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+	"github.com/zchee/llmctxenv/fileio"
+)
+
+type whichCmd struct {
+	logger   *slog.Logger
+	provider contextmanager.Provider
+}
+
+// NewWhichCmd returns the `which` subcommand that shows the context files a provider uses in the current directory.
+func NewWhichCmd() *cobra.Command {
+	w := &whichCmd{
+		logger: slog.Default().WithGroup("which"),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "which",
+		Short: "Show the context files used in the current directory and why",
+		Long: `Show the managed context files of the environment used in the current directory and why the
+environment is selected:
+
+  env          the ` + contextmanager.EnvironmentVariable("<provider>") + ` or ` + contextmanager.EnvEnvironment + ` environment variable, see shell
+  branch-rule  a branch rule of a version file matches the checked out git branch
+  local        the ` + contextmanager.LocalVersionFileName + ` file of the current directory or a parent selects it
+  project      the local context of the project has context files, see local --init
+  global       the global environment selected by use
+  default      nothing selects an environment
+
+The provider CLIs do not read the files shown here but the files installed in their user-wide location,
+which are the same for every shell and directory until activate or the shell-init hook installs the selected
+environment. See status for what is installed, and env for giving a shell its own context files.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.RunE = w.RunWhich
+	cmd.Annotations = readOnly()
+
+	f := cmd.Flags()
+	f.StringVarP((*string)(&w.provider), "provider", "p", "", "manages system context provider name (default all providers)")
+
+	return cmd
+}
+
+// RunWhich runs the `which` subcommand which shows the managed context files of the active environments.
+func (c *whichCmd) RunWhich(cmd *cobra.Command, args []string) error {
+	c.logger.DebugContext(cmd.Context(), "RunWhich",
+		slog.String("provider", c.provider.String()),
+	)
+
+	providers, err := selectProviders(c.provider)
+	if err != nil {
+		return err
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current directory: %w", err)
+	}
+
+	w := cmd.OutOrStdout()
+	for _, provider := range providers {
+		sel, err := contextmanager.SelectEnvironment(provider, dir)
+		if err != nil {
+			return fmt.Errorf("resolve %s environment: %w", provider, err)
+		}
+
+		fmt.Fprintf(w, "%s: %s\n", provider, sel.Name)
+		switch sel.Source() {
+		case contextmanager.SourceEnv:
+			fmt.Fprintf(w, "  %-8s environment variable %s\n", "source:", sel.EnvVar)
+		case contextmanager.SourceBranchRule:
+			fmt.Fprintf(w, "  %-8s branch rule %q of %s on %s\n", "source:", sel.BranchRule, sel.Origin, sel.Branch)
+		case contextmanager.SourceDefault:
+			fmt.Fprintf(w, "  %-8s default, no version file selects an environment\n", "source:")
+		case contextmanager.SourceProject:
+			fmt.Fprintf(w, "  %-8s local context of the project %s\n", "source:", sel.Dir)
+		default:
+			fmt.Fprintf(w, "  %-8s %s %s\n", "source:", sel.Source(), sel.Origin)
+		}
+
+		env, err := sel.Environment()
+		if err != nil {
+			if errors.Is(err, contextmanager.ErrEnvironmentNotExist) {
+				fmt.Fprintf(w, "  %-8s environment %s does not exist\n", "file:", sel.Name)
+				continue
+			}
+			return err
+		}
+		files, err := env.Files()
+		if err != nil {
+			return fmt.Errorf("list %s files: %w", provider, err)
+		}
+		if len(files) == 0 {
+			fmt.Fprintf(w, "  %-8s no context files in %s\n", "file:", env.Dir)
+		}
+		for _, name := range files {
+			if !env.IsRendered() {
+				fmt.Fprintf(w, "  %-8s %s\n", "file:", filepath.Join(env.Dir, name))
+				continue
+			}
+			// Rendered on activation, which is not done here
+			from := filepath.Join(contextmanager.SharedDir(env.Name), contextmanager.CanonicalFile)
+			if override := filepath.Join(env.Dir, name); fileio.IsExist(override) {
+				from += " and " + override
+			}
+			fmt.Fprintf(w, "  %-8s %s (rendered from %s)\n", "file:", filepath.Join(contextmanager.RenderedDir(provider, env.Name), name), from)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,401 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrUnmanagedFile is returned when a target path is occupied by a file which is not managed by llmctxenv.
+	ErrUnmanagedFile = errors.New("file is not managed by llmctxenv")
+
+	// ErrModifiedFile is returned when an installed file holds changes which would be lost by removing it.
+	ErrModifiedFile = errors.New("file has been modified since activation")
+)
+
+// Activate installs the context files of the [Environment] into the [TargetDir] of its provider with the
+// given [Strategy] and records the [Deployment].
+//
+// The new files are staged with the strategy before the previous deployment of the provider is undone, so
+// the previous deployment is kept if the strategy cannot be used for the target directory. Unmanaged files
+// occupying a target path are moved into [BackupDir] and restored by [Deactivate]. It returns an error
+// wrapping [ErrUnmanagedFile] without changing anything if a target path is occupied by something that cannot
+// be backed up, an error wrapping [ErrModifiedFile] if a previously installed file has been changed since it
+// was installed, or an error wrapping [ErrSyncConflict] if a conflict of the previous deployment has not been
+// resolved with [Resolve].
+func Activate(env *Environment, strategy Strategy) (*Deployment, error) {
+	if _, err := ParseStrategy(strategy.String()); err != nil {
+		return nil, err
+	}
+
+	targetDir, err := TargetDir(env.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := env.Files()
+	if err != nil {
+		return nil, fmt.Errorf("list %s environment %s files: %w", env.Provider, env.Name, err)
+	}
+	sourceDir, err := env.SourceDir()
+	if err != nil {
+		return nil, err
+	}
+
+	prev, err := LoadDeployment(env.Provider, ScopeGlobal)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		for _, f := range prev.Files {
+			if f.Conflict {
+				return nil, fmt.Errorf("%s: %w, resolve it first", f.Source, ErrSyncConflict)
+			}
+		}
+	}
+
+	// Check all targets before changing anything
+	var stale []string
+	for _, name := range ContextFiles[env.Provider] {
+		target := filepath.Join(targetDir, name)
+		if _, ok := prev.Lookup(target); ok {
+			continue
+		}
+		ok, err := isManaged(env.Provider, target)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return nil, err
+		case ok:
+			stale = append(stale, target)
+		case slices.Contains(files, name):
+			if !canBackup(target) {
+				return nil, fmt.Errorf("%s: %w", target, ErrUnmanagedFile)
+			}
+		}
+	}
+
+	// Stage the new files next to their targets before changing anything, so that a strategy which cannot
+	// be used for the target directory (e.g. hard links across filesystems) keeps the previous deployment.
+	staged, err := stage(sourceDir, targetDir, files, strategy)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, tmp := range staged {
+			os.Remove(tmp)
+		}
+	}()
+
+	// Backups of the previous deployment are carried over to the new one
+	var backups map[string]*Backup
+	if prev != nil {
+		if backups, err = undeploy(prev, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, target := range stale {
+		if err := os.Remove(target); err != nil {
+			return nil, fmt.Errorf("remove %s: %w", target, err)
+		}
+	}
+
+	// Record the carried over backups before installing anything, so that they are never lost. A file
+	// without Source holds a backup whose target has nothing installed.
+	d := &Deployment{
+		Provider:    env.Provider,
+		Scope:       ScopeGlobal,
+		Environment: env.Name,
+		Strategy:    strategy,
+		Time:        time.Now().UTC(),
+		Files:       make([]DeployedFile, 0, len(files)+len(backups)),
+		Dir:         env.Dir,
+	}
+	for _, target := range slices.Sorted(maps.Keys(backups)) {
+		d.Files = append(d.Files, DeployedFile{Target: target, Backup: backups[target]})
+	}
+	if prev != nil {
+		if err := d.save(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range files {
+		f := d.file(filepath.Join(targetDir, name))
+		if err := f.install(filepath.Join(sourceDir, name), staged[name]); err != nil {
+			return d, errors.Join(err, d.save())
+		}
+	}
+
+	// Restore the backups of the targets which are no longer installed
+	err = d.restorePending()
+	if len(d.Files) == 0 {
+		return nil, errors.Join(err, removeDeployment(d.Provider, d.Scope))
+	}
+
+	return d, errors.Join(err, d.save())
+}
+
+// stage installs the context files in sourceDir with the strategy into temporary paths in targetDir and
+// returns them keyed by the context filename.
+func stage(sourceDir, targetDir string, files []string, strategy Strategy) (map[string]string, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(targetDir, 0o700); err != nil {
+		return nil, fmt.Errorf("mkdir all %s path: %w", targetDir, err)
+	}
+
+	staged := make(map[string]string, len(files))
+	for _, name := range files {
+		source := filepath.Join(sourceDir, name)
+		tmp := stagingPath(filepath.Join(targetDir, name))
+		if err := os.Remove(tmp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("remove %s: %w", tmp, err)
+		}
+		if err := strategy.Install(tmp, source); err != nil {
+			for _, tmp := range staged {
+				os.Remove(tmp)
+			}
+			return nil, fmt.Errorf("install %s to %s: %w", source, targetDir, err)
+		}
+		staged[name] = tmp
+	}
+
+	return staged, nil
+}
+
+// stagingPath returns the temporary path next to path where a new file is prepared before it is renamed
+// to path.
+func stagingPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".llmctxenv-tmp")
+}
+
+// install moves the staged file of source into the target of f. An existing unmanaged target is backed up
+// unless f already has the backup of the target.
+func (f *DeployedFile) install(source, staged string) error {
+	if _, err := os.Lstat(f.Target); err == nil {
+		// The target of a backup which could not be restored is occupied by another unmanaged file
+		if f.Backup != nil {
+			return fmt.Errorf("%s: %w", f.Target, ErrUnmanagedFile)
+		}
+		b, err := backup(f.Target)
+		if err != nil {
+			return err
+		}
+		f.Backup = b
+	}
+
+	digest, err := snapshot(staged)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(staged, f.Target); err != nil {
+		return fmt.Errorf("install %s to %s: %w", source, f.Target, err)
+	}
+	f.Source = source
+	f.Digest = digest
+
+	return nil
+}
+
+// Deactivate removes the context files installed by [Activate] from the location of a given provider and
+// restores the unmanaged files which were there before.
+//
+// It returns the undone [Deployment], or nil if the provider has not been activated. If an installed file has
+// been changed since it was installed, it returns an error wrapping [ErrModifiedFile] without changing
+// anything unless force is true, in which case the changed file is moved into [BackupDir] and returned keyed
+// by its target path before the original file is restored.
+func Deactivate(provider Provider, force bool) (*Deployment, map[string]*Backup, error) {
+	d, err := LoadDeployment(provider, ScopeGlobal)
+	if err != nil || d == nil {
+		return nil, nil, err
+	}
+
+	var saved map[string]*Backup
+	if force {
+		if saved, err = d.saveModified(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if _, err := undeploy(d, true); err != nil {
+		return nil, saved, err
+	}
+
+	return d, saved, nil
+}
+
+// saveModified moves the installed files of d which have been changed since they were installed into
+// [BackupDir].
+func (d *Deployment) saveModified() (map[string]*Backup, error) {
+	saved := make(map[string]*Backup)
+	for _, f := range d.Files {
+		if f.Source == "" {
+			continue
+		}
+		ok, err := f.removable()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return saved, err
+		}
+		if ok {
+			continue
+		}
+		b, err := backup(f.Target)
+		if err != nil {
+			return saved, fmt.Errorf("save modified %s: %w", f.Target, err)
+		}
+		saved[f.Target] = b
+	}
+	return saved, nil
+}
+
+// undeploy removes the files installed by d.
+//
+// If restore is true, the backed up files are restored and the record of d is removed, or rewritten with the
+// backups which could not be restored. Otherwise the record is kept for the caller to replace, and the
+// backups are returned keyed by the target path. It returns an error wrapping [ErrModifiedFile] without
+// changing anything if an installed file has been changed since it was installed.
+func undeploy(d *Deployment, restore bool) (map[string]*Backup, error) {
+	var installed []string
+	for _, f := range d.Files {
+		if f.Source == "" {
+			continue
+		}
+		ok, err := f.removable()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", f.Target, ErrModifiedFile)
+		}
+		installed = append(installed, f.Target)
+	}
+
+	for _, target := range installed {
+		if err := os.Remove(target); err != nil {
+			return nil, fmt.Errorf("remove %s: %w", target, err)
+		}
+	}
+
+	if !restore {
+		backups := make(map[string]*Backup)
+		for _, f := range d.Files {
+			if f.Backup != nil {
+				backups[f.Target] = f.Backup
+			}
+		}
+		return backups, nil
+	}
+
+	for i := range d.Files {
+		d.Files[i].Source = ""
+	}
+	err := d.restorePending()
+	if len(d.Files) == 0 {
+		return nil, errors.Join(err, removeDeployment(d.Provider, d.Scope))
+	}
+
+	return nil, errors.Join(err, d.save())
+}
+
+// file returns the file of d installed at target, adding a new one if there is none.
+func (d *Deployment) file(target string) *DeployedFile {
+	for i := range d.Files {
+		if d.Files[i].Target == target {
+			return &d.Files[i]
+		}
+	}
+	d.Files = append(d.Files, DeployedFile{Target: target})
+	return &d.Files[len(d.Files)-1]
+}
+
+// restorePending restores the backups of the targets of d which have nothing installed, and drops them from
+// d. The backups which could not be restored are kept.
+func (d *Deployment) restorePending() error {
+	var errs []error
+	files := d.Files[:0]
+	for _, f := range d.Files {
+		if f.Source == "" {
+			if f.Backup == nil {
+				continue
+			}
+			if err := f.Backup.restore(f.Target); err != nil {
+				errs = append(errs, err)
+				files = append(files, f)
+			}
+			continue
+		}
+		files = append(files, f)
+	}
+	d.Files = files
+
+	return errors.Join(errs...)
+}
+
+// canBackup reports whether the unmanaged file at path can be moved into [BackupDir].
+func canBackup(path string) bool {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	return fi.Mode().IsRegular() || fi.Mode()&fs.ModeSymlink != 0
+}
+
+// isManaged reports whether path is a symbolic link to a context file of an environment of the provider,
+// which is left behind by llmctxenv without a [Deployment] record.
+//
+// It returns an error wrapping [fs.ErrNotExist] if path does not exist.
+func isManaged(provider Provider, path string) (bool, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		return false, nil
+	}
+
+	dest, err := os.Readlink(path)
+	if err != nil {
+		return false, err
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(filepath.Dir(path), dest)
+	}
+	if !slices.Contains(ContextFiles[provider], filepath.Base(dest)) {
+		return false, nil
+	}
+
+	dir := filepath.Dir(filepath.Clean(dest))
+	parent := filepath.Dir(dir)
+	return dir == GlobalDir(provider) || parent == EnvironmentsDir(provider) || parent == RenderedEnvironmentsDir(provider), nil
+}
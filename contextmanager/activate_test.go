@@ -0,0 +1,397 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+// setupTestHome points the user home directory to a temporary directory for the duration of the test.
+func setupTestHome(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	return home
+}
+
+// createEnvironment creates the named environment with the given context files.
+func createEnvironment(t *testing.T, provider contextmanager.Provider, name string, files map[string]string) *contextmanager.Environment {
+	t.Helper()
+
+	env, err := contextmanager.LookupEnvironment(provider, name)
+	if err != nil {
+		env, err = contextmanager.CreateEnvironment(provider, name)
+		if err != nil {
+			t.Fatalf("CreateEnvironment(%v, %q) unexpected error: %v", provider, name, err)
+		}
+	}
+	if err := os.MkdirAll(env.Dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(env.Dir, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return env
+}
+
+// readFile returns the contents of the file at path.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestActivate(t *testing.T) {
+	for _, strategy := range contextmanager.Strategies() {
+		t.Run(strategy.String(), func(t *testing.T) {
+			setupTestRoot(t)
+			home := setupTestHome(t)
+
+			work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "work"})
+			empty := createEnvironment(t, contextmanager.ProviderClaudeCode, "empty", nil)
+			target := filepath.Join(home, ".claude", "CLAUDE.md")
+
+			d, err := contextmanager.Activate(work, strategy)
+			if err != nil {
+				t.Fatalf("Activate() unexpected error: %v", err)
+			}
+			if len(d.Files) != 1 || d.Files[0].Target != target || d.Strategy != strategy {
+				t.Errorf("Activate() = %+v, want %s installed with %s", d, target, strategy)
+			}
+			if got := readFile(t, target); got != "work" {
+				t.Errorf("deployed content = %q, want %q", got, "work")
+			}
+
+			fi, err := os.Lstat(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isSymlink := fi.Mode()&os.ModeSymlink != 0; isSymlink != (strategy == contextmanager.StrategySymlink) {
+				t.Errorf("deployed file mode = %v with %s strategy", fi.Mode(), strategy)
+			}
+
+			// The strategy is recorded so that the deployment can be undone later
+			recorded, err := contextmanager.LoadDeployment(contextmanager.ProviderClaudeCode, contextmanager.ScopeGlobal)
+			if err != nil {
+				t.Fatalf("LoadDeployment() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(recorded, d) {
+				t.Errorf("LoadDeployment() = %+v, want %+v", recorded, d)
+			}
+
+			// Activating again replaces the managed file
+			if _, err := contextmanager.Activate(work, strategy); err != nil {
+				t.Fatalf("Activate() again unexpected error: %v", err)
+			}
+
+			// Switching to an environment without the file removes the managed file
+			if _, err := contextmanager.Activate(empty, strategy); err != nil {
+				t.Fatalf("Activate(empty) unexpected error: %v", err)
+			}
+			if _, err := os.Lstat(target); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("managed file %s should be removed, got err = %v", target, err)
+			}
+			if recorded, err := contextmanager.LoadDeployment(contextmanager.ProviderClaudeCode, contextmanager.ScopeGlobal); err != nil || recorded != nil {
+				t.Errorf("LoadDeployment() = %+v, %v, want no deployment", recorded, err)
+			}
+		})
+	}
+}
+
+func TestActivate_ModifiedFile(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	work := createEnvironment(t, contextmanager.ProviderCodex, "work", map[string]string{"AGENTS.md": "work"})
+	target := filepath.Join(home, ".codex", "AGENTS.md")
+
+	if _, err := contextmanager.Activate(work, contextmanager.StrategyCopy); err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	if err := os.WriteFile(target, []byte("work\nedited by the CLI"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := contextmanager.Activate(work, contextmanager.StrategyCopy); !errors.Is(err, contextmanager.ErrModifiedFile) {
+		t.Fatalf("Activate() error = %v, want %v", err, contextmanager.ErrModifiedFile)
+	}
+	if got := readFile(t, target); got != "work\nedited by the CLI" {
+		t.Errorf("modified file content = %q, want it untouched", got)
+	}
+}
+
+func TestActivate_InstallFailure(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "work"})
+	oss := createEnvironment(t, contextmanager.ProviderClaudeCode, "oss", map[string]string{"CLAUDE.md": "oss"})
+	target := filepath.Join(home, ".claude", "CLAUDE.md")
+
+	want, err := contextmanager.Activate(work, contextmanager.StrategyCopy)
+	if err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+
+	// Block the staging path so that the installation of the new environment fails
+	if err := os.MkdirAll(filepath.Join(home, ".claude", ".CLAUDE.md.llmctxenv-tmp", "blocker"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := contextmanager.Activate(oss, contextmanager.StrategyCopy); err == nil {
+		t.Fatal("Activate() expected error but got none")
+	}
+
+	if got := readFile(t, target); got != "work" {
+		t.Errorf("deployed content = %q, want the previous deployment %q kept", got, "work")
+	}
+	got, err := contextmanager.LoadDeployment(contextmanager.ProviderClaudeCode, contextmanager.ScopeGlobal)
+	if err != nil {
+		t.Fatalf("LoadDeployment() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadDeployment() = %+v, want the previous deployment %+v", got, want)
+	}
+}
+
+func TestActivate_Backup(t *testing.T) {
+	for _, strategy := range contextmanager.Strategies() {
+		t.Run(strategy.String(), func(t *testing.T) {
+			setupTestRoot(t)
+			home := setupTestHome(t)
+
+			work := createEnvironment(t, contextmanager.ProviderGeminiCLI, "work", map[string]string{"GEMINI.md": "work"})
+			oss := createEnvironment(t, contextmanager.ProviderGeminiCLI, "oss", map[string]string{"GEMINI.md": "oss"})
+			empty := createEnvironment(t, contextmanager.ProviderGeminiCLI, "empty", nil)
+			target := filepath.Join(home, ".gemini", "GEMINI.md")
+			if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(target, []byte("hand-written"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(target, 0o640); err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := contextmanager.Activate(work, strategy)
+			if err != nil {
+				t.Fatalf("Activate() unexpected error: %v", err)
+			}
+			if b := d.Files[0].Backup; b == nil || readFile(t, b.Path()) != "hand-written" {
+				t.Fatalf("Activate() backup = %+v, want the original file backed up", b)
+			}
+			if got := readFile(t, target); got != "work" {
+				t.Errorf("deployed content = %q, want %q", got, "work")
+			}
+
+			// Switching environments keeps the original backup
+			if _, err := contextmanager.Activate(oss, strategy); err != nil {
+				t.Fatalf("Activate(oss) unexpected error: %v", err)
+			}
+			if got := readFile(t, target); got != "oss" {
+				t.Errorf("deployed content = %q, want %q", got, "oss")
+			}
+
+			d, _, err = contextmanager.Deactivate(contextmanager.ProviderGeminiCLI, false)
+			if err != nil {
+				t.Fatalf("Deactivate() unexpected error: %v", err)
+			}
+			if d == nil || d.Environment != "oss" {
+				t.Errorf("Deactivate() = %+v, want oss deployment", d)
+			}
+			assertRestored(t, target, "hand-written", 0o640)
+
+			// Switching to an environment without the file restores the original file
+			if _, err := contextmanager.Activate(work, strategy); err != nil {
+				t.Fatalf("Activate() unexpected error: %v", err)
+			}
+			if _, err := contextmanager.Activate(empty, strategy); err != nil {
+				t.Fatalf("Activate(empty) unexpected error: %v", err)
+			}
+			assertRestored(t, target, "hand-written", 0o640)
+
+			if d, _, err := contextmanager.Deactivate(contextmanager.ProviderGeminiCLI, false); err != nil || d != nil {
+				t.Errorf("Deactivate() on inactive provider = %+v, %v, want nil", d, err)
+			}
+		})
+	}
+}
+
+func TestActivate_BackupSymlink(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "work"})
+	dotfile := filepath.Join(home, "dotfiles", "CLAUDE.md")
+	target := filepath.Join(home, ".claude", "CLAUDE.md")
+	for _, dir := range []string{filepath.Dir(dotfile), filepath.Dir(target)} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(dotfile, []byte("dotfiles"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(dotfile, target); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := contextmanager.Activate(work, contextmanager.StrategyCopy); err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	if _, _, err := contextmanager.Deactivate(contextmanager.ProviderClaudeCode, false); err != nil {
+		t.Fatalf("Deactivate() unexpected error: %v", err)
+	}
+
+	dest, err := os.Readlink(target)
+	if err != nil {
+		t.Fatalf("restored file should be a symlink: %v", err)
+	}
+	if dest != dotfile {
+		t.Errorf("restored symlink = %s, want %s", dest, dotfile)
+	}
+}
+
+func TestDeactivate_Modified(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "work"})
+	target := filepath.Join(home, ".claude", "CLAUDE.md")
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("hand-written"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := contextmanager.Activate(work, contextmanager.StrategyCopy); err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	// The provider CLI rewrites the copy in place
+	if err := os.WriteFile(target, []byte("work\nedited by the CLI"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := contextmanager.Deactivate(contextmanager.ProviderClaudeCode, false); !errors.Is(err, contextmanager.ErrModifiedFile) {
+		t.Fatalf("Deactivate() error = %v, want %v", err, contextmanager.ErrModifiedFile)
+	}
+	if got := readFile(t, target); got != "work\nedited by the CLI" {
+		t.Errorf("modified file content = %q, want it untouched", got)
+	}
+
+	d, saved, err := contextmanager.Deactivate(contextmanager.ProviderClaudeCode, true)
+	if err != nil {
+		t.Fatalf("Deactivate(force) unexpected error: %v", err)
+	}
+	if d == nil {
+		t.Fatal("Deactivate(force) returned no deployment")
+	}
+	b, ok := saved[target]
+	if !ok {
+		t.Fatalf("Deactivate(force) saved = %v, want %s saved", saved, target)
+	}
+	if got := readFile(t, b.Path()); got != "work\nedited by the CLI" {
+		t.Errorf("saved content = %q, want the modified file", got)
+	}
+	assertRestored(t, target, "hand-written", 0o600)
+}
+
+func TestActivate_ForeignSymlinkInRoot(t *testing.T) {
+	root := setupTestRoot(t)
+	home := setupTestHome(t)
+
+	work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "work"})
+	notes := filepath.Join(root, "notes", "CLAUDE.md")
+	target := filepath.Join(home, ".claude", "CLAUDE.md")
+	for _, dir := range []string{filepath.Dir(notes), filepath.Dir(target)} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(notes, []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(notes, target); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := contextmanager.Activate(work, contextmanager.StrategySymlink)
+	if err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	if b := d.Files[0].Backup; b == nil || b.Link != notes {
+		t.Fatalf("Activate() backup = %+v, want the symlink to %s backed up", b, notes)
+	}
+
+	if _, _, err := contextmanager.Deactivate(contextmanager.ProviderClaudeCode, false); err != nil {
+		t.Fatalf("Deactivate() unexpected error: %v", err)
+	}
+	if dest, err := os.Readlink(target); err != nil || dest != notes {
+		t.Errorf("restored symlink = %q, %v, want %s", dest, err, notes)
+	}
+}
+
+// assertRestored asserts that the file at path has the original content and permissions.
+func assertRestored(t *testing.T, path, content string, perm os.FileMode) {
+	t.Helper()
+
+	fi, err := os.Lstat(path)
+	if err != nil {
+		t.Fatalf("original file %s was not restored: %v", path, err)
+	}
+	if !fi.Mode().IsRegular() || fi.Mode().Perm() != perm {
+		t.Errorf("restored file mode = %v, want regular file with %o", fi.Mode(), perm)
+	}
+	if got := readFile(t, path); got != content {
+		t.Errorf("restored content = %q, want %q", got, content)
+	}
+}
+
+func TestActivate_UnmanagedFile(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	work := createEnvironment(t, contextmanager.ProviderGeminiCLI, "work", map[string]string{"GEMINI.md": "work"})
+	target := filepath.Join(home, ".gemini", "GEMINI.md")
+	if err := os.MkdirAll(target, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := contextmanager.Activate(work, contextmanager.DefaultStrategy); !errors.Is(err, contextmanager.ErrUnmanagedFile) {
+		t.Fatalf("Activate() error = %v, want %v", err, contextmanager.ErrUnmanagedFile)
+	}
+	if fi, err := os.Stat(target); err != nil || !fi.IsDir() {
+		t.Errorf("unmanaged directory %s should be untouched: %v", target, err)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/zchee/llmctxenv/fileio"
+)
+
+// Backup records an unmanaged file which was moved aside by [Activate].
+type Backup struct {
+	// Digest is the [fileio.HashFile] digest of the original content which names the file in [BackupDir].
+	Digest string `json:"digest,omitempty"`
+
+	// Mode is the original file mode.
+	Mode fs.FileMode `json:"mode"`
+
+	// Link is the original destination if the original file was a symbolic link.
+	Link string `json:"link,omitempty"`
+}
+
+// BackupDir returns the directory path that holds the backups of the unmanaged files.
+func BackupDir() string {
+	return filepath.Join(LLMCtxEnvRoot, "backups")
+}
+
+// Path returns the path of the backed up content in [BackupDir].
+func (b *Backup) Path() string {
+	if b.Digest == "" {
+		return ""
+	}
+	return filepath.Join(BackupDir(), b.Digest)
+}
+
+// backup moves the unmanaged file at path into [BackupDir].
+func backup(path string) (*Backup, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case fi.Mode()&fs.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove %s: %w", path, err)
+		}
+		return &Backup{Mode: fi.Mode(), Link: link}, nil
+
+	case fi.Mode().IsRegular():
+		digest, err := fileio.HashFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("hash %s: %w", path, err)
+		}
+		b := &Backup{Digest: digest, Mode: fi.Mode()}
+		if err := os.MkdirAll(BackupDir(), 0o700); err != nil {
+			return nil, fmt.Errorf("mkdir all %s path: %w", BackupDir(), err)
+		}
+		if err := moveFile(b.Path(), path, fi.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("back up %s: %w", path, err)
+		}
+		return b, nil
+
+	default:
+		return nil, fmt.Errorf("%s: %w", path, ErrUnmanagedFile)
+	}
+}
+
+// moveFile moves the file at source to dest. The same content may already be backed up to dest.
+func moveFile(dest, source string, perm fs.FileMode) error {
+	if fileio.IsExist(dest) {
+		return os.Remove(source)
+	}
+
+	err := os.Rename(source, dest)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	// Fall back to copy if source and dest are on different filesystems
+	if err := fileio.CopyFile(dest, source, perm); err != nil {
+		return err
+	}
+	return os.Remove(source)
+}
+
+// restore restores the backed up file to path.
+func (b *Backup) restore(path string) error {
+	if _, err := os.Lstat(path); err == nil {
+		return fmt.Errorf("restore %s: %w", path, fs.ErrExist)
+	}
+
+	if b.Link != "" {
+		return os.Symlink(b.Link, path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("mkdir all %s path: %w", filepath.Dir(path), err)
+	}
+	if err := fileio.CopyFile(path, b.Path(), b.Mode.Perm()); err != nil {
+		return fmt.Errorf("restore %s: %w", path, err)
+	}
+	// The permissions given to CopyFile are masked by the umask
+	return os.Chmod(path, b.Mode.Perm())
+}
@@ -0,0 +1,226 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zchee/llmctxenv/gitrepo"
+)
+
+// maxCacheEntries is the number of directories whose selections are kept in the [SelectionCacheFile].
+const maxCacheEntries = 256
+
+// SelectionCacheFile returns the path of the file caching the selections of [CachedSelections].
+func SelectionCacheFile() string {
+	return filepath.Join(LLMCtxEnvRoot, "cache", "selections.json")
+}
+
+// selectionCache maps a cache key of [CachedSelections] to the selections.
+type selectionCache map[string]*cacheEntry
+
+type cacheEntry struct {
+	// Deps are the files the selections depend on, with their modification times when the selections were
+	// made. A zero time records that the file did not exist.
+	Deps map[string]time.Time `json:"deps"`
+
+	Selections []*Selection `json:"selections"`
+
+	// Used is the last time the entry was used, which decides the entries dropped from a full cache.
+	Used time.Time `json:"used"`
+}
+
+// valid reports whether the modification times of the dependencies of e are unchanged.
+func (e *cacheEntry) valid() bool {
+	for path, mtime := range e.Deps {
+		if !modTime(path).Equal(mtime) {
+			return false
+		}
+	}
+	return true
+}
+
+// modTime returns the modification time of the file at path, or the zero time if it does not exist.
+func modTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+// CachedSelections returns the [Selection] of each provider in the directory dir as [SelectEnvironment] does.
+//
+// The selections are cached in the [SelectionCacheFile] by the directory and the environment variables which
+// select environments, and reused while the modification times of the version files in dir and its parents,
+// the [GlobalVersionFile], the [ConfigFile], the local system contexts of the project and the git HEAD of dir
+// are unchanged, so that it is cheap enough to run on every
+// shell prompt.
+func CachedSelections(dir string, providers []Provider) ([]*Selection, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	key := cacheKey(dir, providers)
+	cache := loadSelectionCache()
+	if e, ok := cache[key]; ok && e.valid() {
+		// The entry is not saved again only to update its use time
+		return e.Selections, nil
+	}
+
+	// Record the dependencies before selecting, so that a change made meanwhile invalidates the entry
+	e := &cacheEntry{
+		Deps: selectionDeps(dir),
+		Used: time.Now().UTC(),
+	}
+	for _, provider := range providers {
+		sel, err := SelectEnvironment(provider, dir)
+		if err != nil {
+			return nil, err
+		}
+		e.Selections = append(e.Selections, sel)
+	}
+
+	cache[key] = e
+	if err := cache.save(); err != nil {
+		return nil, fmt.Errorf("save selection cache: %w", err)
+	}
+	return e.Selections, nil
+}
+
+// cacheKey returns the key of the selections of the providers in dir, which includes the environment
+// variables selecting environments.
+func cacheKey(dir string, providers []Provider) string {
+	var b strings.Builder
+	b.WriteString(dir)
+	for _, provider := range providers {
+		fmt.Fprintf(&b, "\x00%s", provider)
+	}
+	for _, key := range append([]string{EnvEnvironment}, envVarNames(providers)...) {
+		if v, ok := os.LookupEnv(key); ok {
+			fmt.Fprintf(&b, "\x00%s=%s", key, v)
+		}
+	}
+	return b.String()
+}
+
+func envVarNames(providers []Provider) []string {
+	names := make([]string, len(providers))
+	for i, provider := range providers {
+		names[i] = EnvironmentVariable(provider)
+	}
+	return names
+}
+
+// selectionDeps returns the modification times of the files the selections in dir depend on.
+func selectionDeps(dir string) map[string]time.Time {
+	deps := make(map[string]time.Time)
+	for d := dir; ; {
+		path := filepath.Join(d, LocalVersionFileName)
+		deps[path] = modTime(path)
+
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+	deps[GlobalVersionFile()] = modTime(GlobalVersionFile())
+
+	// The branch rules depend on the checked out branch. Environments created or removed are tracked by
+	// their parent directories, as [EnvEnvironment] applies only to the providers which have the environment.
+	if repo, err := gitrepo.Find(dir); err == nil {
+		path := filepath.Join(repo.GitDir, "HEAD")
+		deps[path] = modTime(path)
+	}
+	for _, provider := range Providers() {
+		deps[EnvironmentsDir(provider)] = modTime(EnvironmentsDir(provider))
+	}
+	deps[SharedEnvironmentsDir()] = modTime(SharedEnvironmentsDir())
+
+	// The [ProjectEnvironment] is selected by the context files in the local system context of the project,
+	// which is named by the configured project markers and identity.
+	deps[ConfigFile()] = modTime(ConfigFile())
+	if cfg, err := LoadConfig(); err == nil {
+		if _, name, err := localName(dir, cfg); err == nil {
+			for _, provider := range Providers() {
+				path := filepath.Join(LocalEnvironmentsDir(provider), name)
+				deps[path] = modTime(path)
+			}
+		}
+	}
+	return deps
+}
+
+// loadSelectionCache loads the [SelectionCacheFile]. A missing or broken cache is empty.
+func loadSelectionCache() selectionCache {
+	cache := make(selectionCache)
+	data, err := os.ReadFile(SelectionCacheFile())
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return make(selectionCache)
+	}
+	return cache
+}
+
+// save atomically writes c to the [SelectionCacheFile], dropping the least recently used entries beyond
+// [maxCacheEntries].
+func (c selectionCache) save() error {
+	if len(c) > maxCacheEntries {
+		keys := slices.SortedFunc(maps.Keys(c), func(a, b string) int {
+			return cmp.Compare(c[b].Used.UnixNano(), c[a].Used.UnixNano())
+		})
+		for _, key := range keys[maxCacheEntries:] {
+			delete(c, key)
+		}
+	}
+
+	path := SelectionCacheFile()
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("mkdir all %s path: %w", dir, err)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".selections-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Config represents the llmctxenv configuration stored in [ConfigFile].
+//
+//	{
+//	  "strategy": "symlink",
+//	  "providers": {
+//	    "claude": {"strategy": "copy"}
+//	  },
+//	  "project_markers": [".hg"],
+//	  "project_identity": "remote"
+//	}
+type Config struct {
+	// Strategy is the default activation strategy for every provider.
+	Strategy Strategy `json:"strategy,omitempty"`
+
+	// ProjectMarkers are the names of the files or directories which mark the root directory of a project
+	// in addition to the [DefaultProjectMarkers].
+	ProjectMarkers []string `json:"project_markers,omitempty"`
+
+	// ProjectIdentity selects how a project is identified by [LocalDir]. The default is [IdentityPath].
+	ProjectIdentity ProjectIdentity `json:"project_identity,omitempty"`
+
+	// Providers holds the per-provider configuration which takes precedence over the global one.
+	Providers map[Provider]ProviderConfig `json:"providers,omitempty"`
+}
+
+// ProviderConfig represents the per-provider configuration.
+type ProviderConfig struct {
+	// Strategy is the activation strategy of the provider.
+	Strategy Strategy `json:"strategy,omitempty"`
+}
+
+// ProjectIdentity represents how a project is identified to find its local system context.
+type ProjectIdentity string
+
+const (
+	// IdentityPath identifies a project by the path of its root directory.
+	IdentityPath ProjectIdentity = "path"
+
+	// IdentityRemote identifies a project by the normalized URL of the "origin" remote of its git
+	// repository, so every clone and worktree of the repository shares the local system context.
+	// A project without the remote is identified by its path.
+	IdentityRemote ProjectIdentity = "remote"
+)
+
+// ConfigFile returns the path of the llmctxenv configuration file.
+func ConfigFile() string {
+	return filepath.Join(LLMCtxEnvRoot, "config.json")
+}
+
+// LoadConfig loads the llmctxenv configuration from [ConfigFile].
+//
+// It returns the zero [Config] if the file does not exist.
+func LoadConfig() (*Config, error) {
+	path := ConfigFile()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	if c.Strategy != "" {
+		if _, err := ParseStrategy(c.Strategy.String()); err != nil {
+			return err
+		}
+	}
+	for _, marker := range c.ProjectMarkers {
+		if marker == "" || marker == "." || marker == ".." || strings.ContainsRune(marker, filepath.Separator) || strings.ContainsRune(marker, '/') {
+			return fmt.Errorf("invalid project marker %q", marker)
+		}
+	}
+	switch c.ProjectIdentity {
+	case "", IdentityPath, IdentityRemote:
+	default:
+		return fmt.Errorf("unknown project identity %q", c.ProjectIdentity)
+	}
+	for provider, pc := range c.Providers {
+		if _, err := ParseProvider(provider.String()); err != nil {
+			return err
+		}
+		if pc.Strategy != "" {
+			if _, err := ParseStrategy(pc.Strategy.String()); err != nil {
+				return fmt.Errorf("%s: %w", provider, err)
+			}
+		}
+	}
+	return nil
+}
+
+// StrategyFor returns the activation strategy of a given provider.
+func (c *Config) StrategyFor(provider Provider) Strategy {
+	return cmp.Or(c.Providers[provider].Strategy, c.Strategy, DefaultStrategy)
+}
+
+// Markers returns the names which mark the root directory of a project.
+func (c *Config) Markers() []string {
+	return append(slices.Clip(DefaultProjectMarkers), c.ProjectMarkers...)
+}
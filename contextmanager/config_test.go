@@ -0,0 +1,116 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := map[string]struct {
+		data    string // empty means no config file
+		want    map[contextmanager.Provider]contextmanager.Strategy
+		wantErr bool
+	}{
+		"no config file": {
+			want: map[contextmanager.Provider]contextmanager.Strategy{
+				contextmanager.ProviderClaudeCode: contextmanager.StrategySymlink,
+				contextmanager.ProviderCodex:      contextmanager.StrategySymlink,
+			},
+		},
+		"global strategy": {
+			data: `{"strategy": "hardlink"}`,
+			want: map[contextmanager.Provider]contextmanager.Strategy{
+				contextmanager.ProviderClaudeCode: contextmanager.StrategyHardlink,
+				contextmanager.ProviderCodex:      contextmanager.StrategyHardlink,
+			},
+		},
+		"per provider strategy": {
+			data: `{"strategy": "hardlink", "providers": {"claude": {"strategy": "copy"}}}`,
+			want: map[contextmanager.Provider]contextmanager.Strategy{
+				contextmanager.ProviderClaudeCode: contextmanager.StrategyCopy,
+				contextmanager.ProviderCodex:      contextmanager.StrategyHardlink,
+			},
+		},
+		"unknown strategy": {
+			data:    `{"strategy": "rsync"}`,
+			wantErr: true,
+		},
+		"unknown provider": {
+			data:    `{"providers": {"unknown": {"strategy": "copy"}}}`,
+			wantErr: true,
+		},
+		"project markers": {
+			data: `{"project_markers": [".hg", "Cargo.toml"]}`,
+			want: map[contextmanager.Provider]contextmanager.Strategy{
+				contextmanager.ProviderClaudeCode: contextmanager.StrategySymlink,
+			},
+		},
+		"invalid project marker": {
+			data:    `{"project_markers": ["a/b"]}`,
+			wantErr: true,
+		},
+		"unknown project identity": {
+			data:    `{"project_identity": "hash"}`,
+			wantErr: true,
+		},
+		"invalid json": {
+			data:    `{`,
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			setupTestRoot(t)
+			if tt.data != "" {
+				if err := os.WriteFile(contextmanager.ConfigFile(), []byte(tt.data), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg, err := contextmanager.LoadConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for provider, want := range tt.want {
+				if got := cfg.StrategyFor(provider); got != want {
+					t.Errorf("StrategyFor(%v) = %v, want %v", provider, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	t.Parallel()
+
+	for _, strategy := range contextmanager.Strategies() {
+		got, err := contextmanager.ParseStrategy(strategy.String())
+		if err != nil || got != strategy {
+			t.Errorf("ParseStrategy(%q) = %v, %v, want %v", strategy, got, err, strategy)
+		}
+	}
+	if _, err := contextmanager.ParseStrategy("rsync"); err == nil {
+		t.Error("ParseStrategy(\"rsync\") expected error but got none")
+	}
+}
@@ -18,10 +18,12 @@ package contextmanager
 
 import (
 	"cmp"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
-	"strings"
+	"slices"
 )
 
 // EnvRoot is the environment variable that specifies the root directory for llmctxenv context environments.
@@ -74,35 +76,91 @@ var ContextFiles = map[Provider][]string{
 	},
 }
 
+// ErrUnknownProvider is returned when a provider name is not one of the known [Provider] values.
+var ErrUnknownProvider = errors.New("unknown provider")
+
+// Providers returns all known providers sorted by name.
+func Providers() []Provider {
+	return slices.Sorted(maps.Keys(ContextFiles))
+}
+
+// ParseProvider parses s as a known [Provider] name.
+func ParseProvider(s string) (Provider, error) {
+	p := Provider(s)
+	if _, ok := ContextFiles[p]; !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownProvider, s)
+	}
+	return p, nil
+}
+
 // GlobalDir returns the directory path for the global system context of a given provider.
 func GlobalDir(provider Provider) string {
 	return filepath.Join(LLMCtxEnvRoot, "global", provider.String())
 }
 
-var dirnameReplacer = strings.NewReplacer(
-	".", "-",
-	string(filepath.Separator), "-",
-)
+// LocalEnvironmentsDir returns the directory path that holds the local system contexts of a given provider.
+func LocalEnvironmentsDir(provider Provider) string {
+	return filepath.Join(LLMCtxEnvRoot, "local", provider.String())
+}
 
-// LocalDir returns the directory path for the local system context of a given provider.
+// LocalDir returns the directory path for the local system context of a given provider in the project which
+// contains projectDir, or the current directory if projectDir is empty.
+//
+// The project is the [ProjectRoot] of the directory, so every subdirectory of a project maps to the same
+// local system context. The directory is named by [EncodeProjectPath], or by [EncodeRemoteKey] if the
+// project is identified by [IdentityRemote]. Directories named by the legacy encoding are not found until
+// they are migrated by [MigrateLocalDirs].
+//
+// The local system context is used as the [ProjectEnvironment] of the project.
 func LocalDir(provider Provider, projectDir string) (string, error) {
-	path, err := os.Getwd()
+	path := projectDir
+	if path == "" {
+		wd, err := workingDir()
+		if err != nil {
+			return "", err
+		}
+		path = wd
+	}
+
+	cfg, err := LoadConfig()
 	if err != nil {
-		return "", fmt.Errorf("get current directory: %w", err)
+		return "", fmt.Errorf("load config: %w", err)
 	}
+	_, name, err := localName(path, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(LocalEnvironmentsDir(provider), name), nil
+}
+
+// localName returns the [ProjectRoot] of the directory path and the name of its local system context
+// directory in [LocalEnvironmentsDir].
+func localName(path string, cfg *Config) (root, name string, err error) {
 	if !filepath.IsAbs(path) {
-		if abs, err := filepath.Abs(path); err == nil {
-			path = abs
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", "", fmt.Errorf("get absolute path of %s: %w", path, err)
 		}
+		path = abs
 	}
-
-	home, err := os.UserHomeDir()
+	root, err = ProjectRoot(path, cfg.Markers())
 	if err != nil {
-		return "", fmt.Errorf("get current user home directory: %w", err)
+		return "", "", err
 	}
 
-	path = strings.TrimPrefix(path, home+string(filepath.Separator))
-	sanitized := dirnameReplacer.Replace(path)
-
-	return filepath.Join(LLMCtxEnvRoot, "local", provider.String(), sanitized), nil
+	if cfg.ProjectIdentity == IdentityRemote {
+		key, ok, err := remoteKey(root)
+		if err != nil {
+			return "", "", err
+		}
+		if ok {
+			return root, EncodeRemoteKey(key), nil
+		}
+	}
+	name, err = EncodeProjectPath(root)
+	if err != nil {
+		return "", "", err
+	}
+	return root, name, nil
 }
@@ -317,7 +317,7 @@ func TestLocalDir(t *testing.T) {
 }
 
 func TestPathSanitization(t *testing.T) {
-	// Test the dirnameReplacer logic by testing what LocalDir would do
+	// Test the legacy dirnameReplacer logic which MigrateLocalDirs recovers the project paths from
 	// This helps us test the sanitization logic in isolation
 	tests := map[string]struct {
 		input    string
@@ -0,0 +1,130 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"time"
+)
+
+// Scope represents where the context files of a [Provider] are installed.
+type Scope string
+
+// String returns a string representation of the [Scope].
+func (s Scope) String() string { return string(s) }
+
+// ScopeGlobal is the [Scope] of the user-wide provider location returned by [TargetDir].
+const ScopeGlobal Scope = "global"
+
+// Deployment records the context files installed into the location of a [Provider] by [Activate].
+type Deployment struct {
+	Provider    Provider       `json:"provider"`
+	Scope       Scope          `json:"scope"`
+	Environment string         `json:"environment"`
+	Strategy    Strategy       `json:"strategy"`
+	Time        time.Time      `json:"time"` // when the environment was activated
+	Files       []DeployedFile `json:"files"`
+
+	// Dir is the directory of the environment, which tells apart the [ProjectEnvironment] of the projects.
+	Dir string `json:"dir,omitempty"`
+}
+
+// Deploys reports whether d is the deployment of the named environment in the directory dir.
+func (d *Deployment) Deploys(name, dir string) bool {
+	return d.Environment == name && (name != ProjectEnvironment || d.Dir == dir)
+}
+
+// environment returns the deployed [Environment].
+//
+// It returns an error wrapping [ErrEnvironmentNotExist] if the environment has been removed since.
+func (d *Deployment) environment() (*Environment, error) {
+	if d.Environment == ProjectEnvironment && d.Dir != "" {
+		return &Environment{
+			Provider: d.Provider,
+			Name:     d.Environment,
+			Dir:      d.Dir,
+		}, nil
+	}
+	return LookupEnvironment(d.Provider, d.Environment)
+}
+
+// DeployedFile records a single installed context file.
+type DeployedFile struct {
+	// Source is the managed file path in [LLMCtxEnvRoot]. It is empty if nothing is installed at Target and
+	// only the Backup of the target remains to be restored.
+	Source string `json:"source,omitempty"`
+
+	// Target is the installed file path in the provider location.
+	Target string `json:"target"`
+
+	// Digest is the [fileio.HashFile] digest of the installed content at the time of the installation. The
+	// content is kept in [BaseDir].
+	Digest string `json:"digest,omitempty"`
+
+	// Conflict reports whether the changes made to the installed file conflicted with the changes made to
+	// Source, which holds the conflict markers until [Resolve].
+	Conflict bool `json:"conflict,omitempty"`
+
+	// Backup is the unmanaged file which occupied Target before the installation, if any.
+	Backup *Backup `json:"backup,omitempty"`
+}
+
+// LoadDeployment loads the [Deployment] of a given provider and scope from the [State].
+//
+// It returns nil if the provider has not been activated in the scope.
+func LoadDeployment(provider Provider, scope Scope) (*Deployment, error) {
+	st, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
+	return st.Lookup(provider, scope), nil
+}
+
+// save records d in the [State].
+func (d *Deployment) save() error {
+	st, err := LoadState()
+	if err != nil {
+		return err
+	}
+	st.put(d)
+	return st.save()
+}
+
+// Lookup returns the deployed file installed at target.
+func (d *Deployment) Lookup(target string) (DeployedFile, bool) {
+	if d == nil {
+		return DeployedFile{}, false
+	}
+	for _, f := range d.Files {
+		if f.Target == target {
+			return f, true
+		}
+	}
+	return DeployedFile{}, false
+}
+
+// removeDeployment removes the [Deployment] of a given provider and scope from the [State].
+func removeDeployment(provider Provider, scope Scope) error {
+	st, err := LoadState()
+	if err != nil {
+		return err
+	}
+	if st.Lookup(provider, scope) == nil {
+		return nil
+	}
+	st.delete(provider, scope)
+	return st.save()
+}
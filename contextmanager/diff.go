@@ -0,0 +1,144 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/zchee/llmctxenv/textdiff"
+)
+
+// devNull is the file name shown in a unified diff for a file which does not exist.
+const devNull = "/dev/null"
+
+// DiffInstalled returns the unified diff from the context files of env to the files installed in the
+// provider location, which are the files the provider CLI actually reads.
+//
+// It returns an empty string if the provider location holds the same content as env. The context files of a
+// rendered environment are rendered in memory, so nothing is written.
+func DiffInstalled(env *Environment) (string, error) {
+	targetDir, err := TargetDir(env.Provider)
+	if err != nil {
+		return "", err
+	}
+	sourceDir, sources, err := env.sourceFiles()
+	if err != nil {
+		return "", err
+	}
+	targets, err := readContextFiles(env.Provider, targetDir)
+	if err != nil {
+		return "", err
+	}
+	return diffFiles(env.Provider, sourceDir, sources, targetDir, targets), nil
+}
+
+// DiffEnvironments returns the unified diff from the context files of the environment a to those of b.
+//
+// It returns an empty string if both environments hold the same content. The context files of a rendered
+// environment are rendered in memory, so nothing is written.
+func DiffEnvironments(a, b *Environment) (string, error) {
+	if a.Provider != b.Provider {
+		return "", fmt.Errorf("cannot compare %s environment %s with %s environment %s", a.Provider, a.Name, b.Provider, b.Name)
+	}
+	aDir, aFiles, err := a.sourceFiles()
+	if err != nil {
+		return "", err
+	}
+	bDir, bFiles, err := b.sourceFiles()
+	if err != nil {
+		return "", err
+	}
+	return diffFiles(a.Provider, aDir, aFiles, bDir, bFiles), nil
+}
+
+// sourceFiles returns the directory path which holds the context files of the [Environment] to be installed
+// as [Environment.SourceDir] does, and the contents of the files keyed by the file name. The files of a
+// rendered environment are rendered in memory rather than written.
+func (e *Environment) sourceFiles() (string, map[string]string, error) {
+	rendered, err := e.renderFiles()
+	if err != nil {
+		return "", nil, err
+	}
+	if rendered == nil {
+		files, err := readContextFiles(e.Provider, e.Dir)
+		return e.Dir, files, err
+	}
+
+	files := make(map[string]string, len(rendered))
+	for name, data := range rendered {
+		files[name] = string(data)
+	}
+	return RenderedDir(e.Provider, e.Name), files, nil
+}
+
+// readContextFiles returns the contents of the context files of the provider in dir keyed by the file name.
+// A file which does not exist is omitted.
+func readContextFiles(provider Provider, dir string) (map[string]string, error) {
+	files := make(map[string]string)
+	for _, name := range ContextFiles[provider] {
+		data, ok, err := readFileIfExist(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			files[name] = data
+		}
+	}
+	return files, nil
+}
+
+// diffFiles returns the unified diff of the context files of the provider from the contents old of the files
+// in oldDir to the contents new of the files in newDir.
+//
+// A file which does not exist is compared as an empty file named [devNull].
+func diffFiles(provider Provider, oldDir string, old map[string]string, newDir string, new map[string]string) string {
+	var diff string
+	for _, name := range ContextFiles[provider] {
+		oldPath, newPath := filepath.Join(oldDir, name), filepath.Join(newDir, name)
+		oldText, oldOK := old[name]
+		newText, newOK := new[name]
+
+		switch {
+		case !oldOK && !newOK:
+			continue
+		case !oldOK:
+			oldPath = devNull
+		case !newOK:
+			newPath = devNull
+		}
+		diff += textdiff.Unified(oldPath, newPath, oldText, newText)
+	}
+	return diff
+}
+
+// readFileIfExist reads the file at path following symbolic links.
+//
+// It reports false without an error if the file or the destination of a symbolic link does not exist.
+func readFileIfExist(path string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return string(data), true, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestDiffInstalled(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "rules\n"})
+	target := filepath.Join(home, ".claude", "CLAUDE.md")
+
+	diff, err := contextmanager.DiffInstalled(work)
+	if err != nil {
+		t.Fatalf("DiffInstalled() unexpected error: %v", err)
+	}
+	want := "--- " + filepath.Join(work.Dir, "CLAUDE.md") + "\n+++ /dev/null\n@@ -1 +0,0 @@\n-rules\n"
+	if diff != want {
+		t.Errorf("DiffInstalled() before activation =\n%s\nwant:\n%s", diff, want)
+	}
+
+	if _, err := contextmanager.Activate(work, contextmanager.StrategyCopy); err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	if diff, err := contextmanager.DiffInstalled(work); err != nil || diff != "" {
+		t.Errorf("DiffInstalled() after activation = %q, %v, want no diff", diff, err)
+	}
+
+	if err := os.WriteFile(target, []byte("rules\nmemory\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	diff, err = contextmanager.DiffInstalled(work)
+	if err != nil {
+		t.Fatalf("DiffInstalled() unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(diff, "+++ "+target+"\n@@ -1 +1,2 @@\n rules\n+memory\n") {
+		t.Errorf("DiffInstalled() after modification =\n%s", diff)
+	}
+}
+
+func TestDiffEnvironments(t *testing.T) {
+	setupTestRoot(t)
+
+	work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "a\nb\n"})
+	review := createEnvironment(t, contextmanager.ProviderClaudeCode, "review", map[string]string{"CLAUDE.md": "a\nc\n"})
+
+	diff, err := contextmanager.DiffEnvironments(work, review)
+	if err != nil {
+		t.Fatalf("DiffEnvironments() unexpected error: %v", err)
+	}
+	want := "--- " + filepath.Join(work.Dir, "CLAUDE.md") + "\n+++ " + filepath.Join(review.Dir, "CLAUDE.md") + "\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n"
+	if diff != want {
+		t.Errorf("DiffEnvironments() =\n%s\nwant:\n%s", diff, want)
+	}
+
+	if diff, err := contextmanager.DiffEnvironments(work, work); err != nil || diff != "" {
+		t.Errorf("DiffEnvironments() of the same environment = %q, %v, want no diff", diff, err)
+	}
+
+	codex := createEnvironment(t, contextmanager.ProviderCodex, "work", map[string]string{"AGENTS.md": "a\n"})
+	if _, err := contextmanager.DiffEnvironments(work, codex); err == nil {
+		t.Error("DiffEnvironments() of different providers should fail")
+	}
+}
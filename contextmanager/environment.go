@@ -0,0 +1,256 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/zchee/llmctxenv/fileio"
+)
+
+// DefaultEnvironment is the name of the implicit environment backed by [GlobalDir].
+const DefaultEnvironment = "global"
+
+// ProjectEnvironment is the name of the implicit environment backed by the [LocalDir] of the project
+// containing the current directory.
+const ProjectEnvironment = "project"
+
+// ErrEnvironmentNotExist is returned when a named environment does not exist.
+var ErrEnvironmentNotExist = errors.New("environment does not exist")
+
+// Environment represents a named set of system context files for a [Provider].
+type Environment struct {
+	Provider Provider
+	Name     string
+	Dir      string
+}
+
+// Files returns the names of the context files of the [Environment] that exist on disk, or every context file
+// of the provider if they are rendered from the [CanonicalFile].
+func (e *Environment) Files() ([]string, error) {
+	if e.IsRendered() {
+		return slices.Clone(ContextFiles[e.Provider]), nil
+	}
+
+	var files []string
+	for _, name := range ContextFiles[e.Provider] {
+		fi, err := os.Stat(filepath.Join(e.Dir, name))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		if fi.Mode().IsRegular() {
+			files = append(files, name)
+		}
+	}
+	return files, nil
+}
+
+// EnvironmentsDir returns the directory path that holds the named environments of a given provider.
+func EnvironmentsDir(provider Provider) string {
+	return filepath.Join(LLMCtxEnvRoot, "envs", provider.String())
+}
+
+// EnvironmentDir returns the directory path for the named environment of a given provider.
+//
+// The [DefaultEnvironment] is stored in [GlobalDir].
+func EnvironmentDir(provider Provider, name string) string {
+	if name == DefaultEnvironment {
+		return GlobalDir(provider)
+	}
+	return filepath.Join(EnvironmentsDir(provider), name)
+}
+
+// ValidateEnvironmentName reports whether name can be used as an environment name.
+func ValidateEnvironmentName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("environment name must be not empty")
+	case name == "." || name == "..":
+		return fmt.Errorf("invalid environment name %q", name)
+	case strings.HasPrefix(name, "."), strings.HasPrefix(name, "-"):
+		return fmt.Errorf("environment name %q must not start with %q", name, name[:1])
+	case strings.ContainsAny(name, `/\=#`) || strings.ContainsFunc(name, isSpace):
+		return fmt.Errorf("environment name %q must not contain path separators, '=', '#' or spaces", name)
+	}
+	return nil
+}
+
+// isImplicit reports whether name is the name of an implicit environment, which is never created.
+func isImplicit(name string) bool {
+	return name == DefaultEnvironment || name == ProjectEnvironment
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// CreateEnvironment creates the named environment of a given provider.
+//
+// It returns an error wrapping [fs.ErrExist] if the environment already exists.
+func CreateEnvironment(provider Provider, name string) (*Environment, error) {
+	if err := ValidateEnvironmentName(name); err != nil {
+		return nil, err
+	}
+	if isImplicit(name) {
+		return nil, fmt.Errorf("%s environment %s: %w", provider, name, fs.ErrExist)
+	}
+
+	dir := EnvironmentDir(provider, name)
+	if fileio.IsExist(dir) {
+		return nil, fmt.Errorf("%s environment %s: %w", provider, name, fs.ErrExist)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("mkdir all %s path: %w", dir, err)
+	}
+
+	return &Environment{
+		Provider: provider,
+		Name:     name,
+		Dir:      dir,
+	}, nil
+}
+
+// LookupEnvironment returns the named environment of a given provider.
+//
+// It returns an error wrapping [ErrEnvironmentNotExist] if the environment does not exist.
+// The [DefaultEnvironment] and the [ProjectEnvironment] always exist, and a shared environment in [SharedDir]
+// exists for every provider.
+func LookupEnvironment(provider Provider, name string) (*Environment, error) {
+	if err := ValidateEnvironmentName(name); err != nil {
+		return nil, err
+	}
+	if name == ProjectEnvironment {
+		dir, err := LocalDir(provider, "")
+		if err != nil {
+			return nil, err
+		}
+		return &Environment{
+			Provider: provider,
+			Name:     name,
+			Dir:      dir,
+		}, nil
+	}
+
+	dir := EnvironmentDir(provider, name)
+	if name != DefaultEnvironment && !isShared(name) {
+		fi, err := os.Stat(dir)
+		if err != nil || !fi.IsDir() {
+			return nil, fmt.Errorf("%s environment %s: %w", provider, name, ErrEnvironmentNotExist)
+		}
+	}
+
+	return &Environment{
+		Provider: provider,
+		Name:     name,
+		Dir:      dir,
+	}, nil
+}
+
+// Environments returns the sorted names of the environments of a given provider, including the environments
+// shared by the providers.
+//
+// The [DefaultEnvironment] is always the first element.
+func Environments(provider Provider) ([]string, error) {
+	names := []string{DefaultEnvironment}
+
+	var envs []string
+	for _, dir := range []string{EnvironmentsDir(provider), SharedEnvironmentsDir()} {
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("ReadDir %s: %w", dir, err)
+		}
+		for _, ent := range ents {
+			if !ent.IsDir() || ValidateEnvironmentName(ent.Name()) != nil || isImplicit(ent.Name()) {
+				continue
+			}
+			envs = append(envs, ent.Name())
+		}
+	}
+	slices.Sort(envs)
+
+	return append(names, slices.Compact(envs)...), nil
+}
+
+// GlobalVersionFile returns the path of the version file that selects the global environments.
+func GlobalVersionFile() string {
+	return filepath.Join(LLMCtxEnvRoot, "version")
+}
+
+// GlobalEnvironment returns the name of the global environment of a given provider.
+//
+// It returns [DefaultEnvironment] if no environment has been selected with [SetGlobalEnvironment].
+func GlobalEnvironment(provider Provider) (string, error) {
+	v, err := ReadVersionFile(GlobalVersionFile())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return DefaultEnvironment, nil
+		}
+		return "", err
+	}
+
+	if name, ok := v.Lookup(provider); ok {
+		return name, nil
+	}
+	return DefaultEnvironment, nil
+}
+
+// SetGlobalEnvironment selects the named environment as the global environment of a given provider.
+func SetGlobalEnvironment(provider Provider, name string) error {
+	if _, err := LookupEnvironment(provider, name); err != nil {
+		return err
+	}
+
+	path := GlobalVersionFile()
+	v, err := ReadVersionFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		v = &Version{}
+	}
+	v.Set(provider, name)
+
+	return WriteVersionFile(path, v)
+}
+
+// ResolveEnvironment returns the active environment of a given provider in the current directory.
+//
+// See [SelectEnvironment] for how the environment is selected.
+func ResolveEnvironment(provider Provider) (*Environment, error) {
+	dir, err := workingDir()
+	if err != nil {
+		return nil, err
+	}
+	sel, err := SelectEnvironment(provider, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return sel.Environment()
+}
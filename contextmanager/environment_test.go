@@ -0,0 +1,249 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+// setupTestRoot points [contextmanager.LLMCtxEnvRoot] to a temporary directory for the duration of the test.
+func setupTestRoot(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	original := contextmanager.LLMCtxEnvRoot
+	contextmanager.LLMCtxEnvRoot = root
+	t.Cleanup(func() {
+		contextmanager.LLMCtxEnvRoot = original
+	})
+
+	return root
+}
+
+func TestValidateEnvironmentName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		name    string
+		wantErr bool
+	}{
+		"simple":           {name: "work", wantErr: false},
+		"with dash":        {name: "code-review", wantErr: false},
+		"with dot":         {name: "v1.2", wantErr: false},
+		"empty":            {name: "", wantErr: true},
+		"dot":              {name: ".", wantErr: true},
+		"dot dot":          {name: "..", wantErr: true},
+		"hidden":           {name: ".work", wantErr: true},
+		"leading dash":     {name: "-work", wantErr: true},
+		"slash":            {name: "a/b", wantErr: true},
+		"equal":            {name: "a=b", wantErr: true},
+		"comment":          {name: "a#b", wantErr: true},
+		"space":            {name: "a b", wantErr: true},
+		"default is valid": {name: contextmanager.DefaultEnvironment, wantErr: false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := contextmanager.ValidateEnvironmentName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEnvironmentName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvironmentDir(t *testing.T) {
+	root := setupTestRoot(t)
+
+	tests := map[string]struct {
+		provider contextmanager.Provider
+		name     string
+		want     string
+	}{
+		"default environment": {
+			provider: contextmanager.ProviderClaudeCode,
+			name:     contextmanager.DefaultEnvironment,
+			want:     filepath.Join(root, "global", "claude"),
+		},
+		"named environment": {
+			provider: contextmanager.ProviderClaudeCode,
+			name:     "work",
+			want:     filepath.Join(root, "envs", "claude", "work"),
+		},
+		"named environment of other provider": {
+			provider: contextmanager.ProviderCodex,
+			name:     "oss",
+			want:     filepath.Join(root, "envs", "codex", "oss"),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := contextmanager.EnvironmentDir(tt.provider, tt.name); got != tt.want {
+				t.Errorf("EnvironmentDir(%v, %q) = %v, want %v", tt.provider, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateEnvironment(t *testing.T) {
+	setupTestRoot(t)
+
+	env, err := contextmanager.CreateEnvironment(contextmanager.ProviderClaudeCode, "work")
+	if err != nil {
+		t.Fatalf("CreateEnvironment() unexpected error: %v", err)
+	}
+	if fi, err := os.Stat(env.Dir); err != nil || !fi.IsDir() {
+		t.Fatalf("environment directory %s was not created: %v", env.Dir, err)
+	}
+
+	if _, err := contextmanager.CreateEnvironment(contextmanager.ProviderClaudeCode, "work"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("CreateEnvironment() on existing environment error = %v, want %v", err, fs.ErrExist)
+	}
+	if _, err := contextmanager.CreateEnvironment(contextmanager.ProviderClaudeCode, contextmanager.DefaultEnvironment); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("CreateEnvironment() on default environment error = %v, want %v", err, fs.ErrExist)
+	}
+	if _, err := contextmanager.CreateEnvironment(contextmanager.ProviderClaudeCode, "../escape"); err == nil {
+		t.Error("CreateEnvironment() with invalid name expected error but got none")
+	}
+}
+
+func TestEnvironments(t *testing.T) {
+	setupTestRoot(t)
+
+	got, err := contextmanager.Environments(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("Environments() unexpected error: %v", err)
+	}
+	if want := []string{contextmanager.DefaultEnvironment}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Environments() = %v, want %v", got, want)
+	}
+
+	for _, name := range []string{"work", "review", "oss"} {
+		if _, err := contextmanager.CreateEnvironment(contextmanager.ProviderClaudeCode, name); err != nil {
+			t.Fatalf("CreateEnvironment(%q) unexpected error: %v", name, err)
+		}
+	}
+	if _, err := contextmanager.CreateEnvironment(contextmanager.ProviderCodex, "codex-only"); err != nil {
+		t.Fatalf("CreateEnvironment() unexpected error: %v", err)
+	}
+
+	got, err = contextmanager.Environments(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("Environments() unexpected error: %v", err)
+	}
+	if want := []string{contextmanager.DefaultEnvironment, "oss", "review", "work"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Environments() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveEnvironment(t *testing.T) {
+	root := setupTestRoot(t)
+
+	env, err := contextmanager.ResolveEnvironment(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("ResolveEnvironment() unexpected error: %v", err)
+	}
+	if env.Name != contextmanager.DefaultEnvironment || env.Dir != contextmanager.GlobalDir(contextmanager.ProviderClaudeCode) {
+		t.Errorf("ResolveEnvironment() = %+v, want default environment", env)
+	}
+
+	if err := contextmanager.SetGlobalEnvironment(contextmanager.ProviderClaudeCode, "work"); !errors.Is(err, contextmanager.ErrEnvironmentNotExist) {
+		t.Errorf("SetGlobalEnvironment() on missing environment error = %v, want %v", err, contextmanager.ErrEnvironmentNotExist)
+	}
+
+	if _, err := contextmanager.CreateEnvironment(contextmanager.ProviderClaudeCode, "work"); err != nil {
+		t.Fatalf("CreateEnvironment() unexpected error: %v", err)
+	}
+	if err := contextmanager.SetGlobalEnvironment(contextmanager.ProviderClaudeCode, "work"); err != nil {
+		t.Fatalf("SetGlobalEnvironment() unexpected error: %v", err)
+	}
+
+	env, err = contextmanager.ResolveEnvironment(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("ResolveEnvironment() unexpected error: %v", err)
+	}
+	if want := filepath.Join(root, "envs", "claude", "work"); env.Name != "work" || env.Dir != want {
+		t.Errorf("ResolveEnvironment() = %+v, want work environment at %s", env, want)
+	}
+
+	// Other providers are not affected
+	env, err = contextmanager.ResolveEnvironment(contextmanager.ProviderCodex)
+	if err != nil {
+		t.Fatalf("ResolveEnvironment() unexpected error: %v", err)
+	}
+	if env.Name != contextmanager.DefaultEnvironment {
+		t.Errorf("ResolveEnvironment(codex) = %q, want %q", env.Name, contextmanager.DefaultEnvironment)
+	}
+}
+
+func TestEnvironment_Files(t *testing.T) {
+	setupTestRoot(t)
+
+	env, err := contextmanager.CreateEnvironment(contextmanager.ProviderClaudeCode, "work")
+	if err != nil {
+		t.Fatalf("CreateEnvironment() unexpected error: %v", err)
+	}
+	for _, name := range []string{"CLAUDE.md", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(env.Dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := env.Files()
+	if err != nil {
+		t.Fatalf("Files() unexpected error: %v", err)
+	}
+	if want := []string{"CLAUDE.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveEnvironment_RemovedEnvironment(t *testing.T) {
+	setupTestRoot(t)
+
+	env := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", nil)
+	if err := contextmanager.SetGlobalEnvironment(contextmanager.ProviderClaudeCode, "work"); err != nil {
+		t.Fatalf("SetGlobalEnvironment() unexpected error: %v", err)
+	}
+	if err := os.RemoveAll(env.Dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := contextmanager.ResolveEnvironment(contextmanager.ProviderClaudeCode); !errors.Is(err, contextmanager.ErrEnvironmentNotExist) {
+		t.Errorf("ResolveEnvironment() error = %v, want %v", err, contextmanager.ErrEnvironmentNotExist)
+	}
+	// The selected name is still reported so that it can be shown as missing
+	if got, err := contextmanager.GlobalEnvironment(contextmanager.ProviderClaudeCode); err != nil || got != "work" {
+		t.Errorf("GlobalEnvironment() = %q, %v, want %q", got, err, "work")
+	}
+
+	// Selecting another environment recovers from the broken selection
+	if err := contextmanager.SetGlobalEnvironment(contextmanager.ProviderClaudeCode, contextmanager.DefaultEnvironment); err != nil {
+		t.Fatalf("SetGlobalEnvironment() unexpected error: %v", err)
+	}
+	if env, err := contextmanager.ResolveEnvironment(contextmanager.ProviderClaudeCode); err != nil || env.Name != contextmanager.DefaultEnvironment {
+		t.Errorf("ResolveEnvironment() = %+v, %v, want default environment", env, err)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Follow activates the environment selected in the directory dir for each of the providers whose installed
+// environment differs. It is run by the shell hook on every directory change, so that entering a project
+// activates its local environment and leaving it restores the global one.
+//
+// The selections are made by [CachedSelections]. The [Strategy] of the current [Deployment] is kept, a
+// provider without a deployment has nothing installed and is activated with the configured strategy only if
+// the selected environment has context files. A failure of a provider does not prevent the others from being
+// activated; the errors are joined.
+//
+// It returns the selections of the activated environments.
+func Follow(dir string, providers []Provider) ([]*Selection, error) {
+	sels, err := CachedSelections(dir, providers)
+	if err != nil {
+		return nil, err
+	}
+	st, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *Config
+	var activated []*Selection
+	var errs []error
+	for _, sel := range sels {
+		prev := st.Lookup(sel.Provider, ScopeGlobal)
+		if prev != nil && prev.Deploys(sel.Name, sel.Dir) {
+			continue
+		}
+
+		env, err := sel.Environment()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var strategy Strategy
+		if prev != nil {
+			strategy = prev.Strategy
+		} else {
+			files, err := env.Files()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("list %s environment %s files: %w", env.Provider, env.Name, err))
+				continue
+			}
+			if len(files) == 0 {
+				continue
+			}
+			if cfg == nil {
+				if cfg, err = LoadConfig(); err != nil {
+					return activated, fmt.Errorf("load config: %w", err)
+				}
+			}
+			strategy = cfg.StrategyFor(sel.Provider)
+		}
+
+		if _, err := Activate(env, strategy); err != nil {
+			errs = append(errs, fmt.Errorf("activate %s environment %s: %w", sel.Provider, sel.Name, err))
+			continue
+		}
+		activated = append(activated, sel)
+	}
+
+	return activated, errors.Join(errs...)
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestCachedSelections(t *testing.T) {
+	setupTestRoot(t)
+	createEnvironment(t, contextmanager.ProviderClaudeCode, "review", nil)
+	createEnvironment(t, contextmanager.ProviderClaudeCode, "work", nil)
+	project := t.TempDir()
+	dir := filepath.Join(project, "sub")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	providers := []contextmanager.Provider{contextmanager.ProviderClaudeCode}
+
+	check := func(want string) {
+		t.Helper()
+		sels, err := contextmanager.CachedSelections(dir, providers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sels) != 1 || sels[0].Name != want {
+			t.Errorf("CachedSelections() = %+v, want %s", sels, want)
+		}
+	}
+
+	check(contextmanager.DefaultEnvironment)
+	if _, err := os.Stat(contextmanager.SelectionCacheFile()); err != nil {
+		t.Fatalf("selection cache has not been written: %v", err)
+	}
+
+	// Creating a version file invalidates the entry
+	path := writeVersionFile(t, project, "claude=review\n")
+	check("review")
+
+	// So does changing it, which is detected by its modification time
+	if err := os.WriteFile(path, []byte("claude=work\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	check("work")
+
+	// An unchanged modification time reuses the cached selection
+	if err := os.WriteFile(path, []byte("claude=review\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	check("work")
+
+	// The environment variables are a part of the key
+	t.Setenv(contextmanager.EnvironmentVariable(contextmanager.ProviderClaudeCode), "review")
+	check("review")
+
+	// A broken cache is ignored
+	if err := os.WriteFile(contextmanager.SelectionCacheFile(), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	check("review")
+}
+
+func TestFollow(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+	provider := contextmanager.ProviderClaudeCode
+	global := createEnvironment(t, provider, contextmanager.DefaultEnvironment, map[string]string{"CLAUDE.md": "global\n"})
+	createEnvironment(t, provider, "review", map[string]string{"CLAUDE.md": "review\n"})
+	createEnvironment(t, contextmanager.ProviderCodex, "review", map[string]string{"AGENTS.md": "review\n"})
+	project := t.TempDir()
+	writeVersionFile(t, project, "claude=review\ncodex=review\n")
+	providers := []contextmanager.Provider{provider, contextmanager.ProviderCodex}
+
+	if _, err := contextmanager.Activate(global, contextmanager.StrategyCopy); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(home, ".claude", "CLAUDE.md")
+	codexTarget := filepath.Join(home, ".codex", "AGENTS.md")
+
+	// Entering the project activates its environments, keeping the strategy of the deployment
+	activated, err := contextmanager.Follow(project, providers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activated) != 2 {
+		t.Fatalf("Follow(project) = %+v, want claude and codex activated", activated)
+	}
+	for _, sel := range activated {
+		want := contextmanager.StrategySymlink
+		if sel.Provider == provider {
+			want = contextmanager.StrategyCopy
+		}
+		d, err := contextmanager.LoadDeployment(sel.Provider, contextmanager.ScopeGlobal)
+		if err != nil || d == nil || d.Environment != "review" || d.Strategy != want {
+			t.Errorf("%s deployment = %+v, %v, want review by %s", sel.Provider, d, err, want)
+		}
+	}
+	if got := readFile(t, target); got != "review\n" {
+		t.Errorf("%s = %q, want %q", target, got, "review\n")
+	}
+
+	// Nothing changes while the selections are the same
+	if activated, err := contextmanager.Follow(project, providers); err != nil || len(activated) != 0 {
+		t.Errorf("Follow(project) again = %+v, %v, want nothing", activated, err)
+	}
+
+	// Leaving the project restores the global environments
+	if activated, err := contextmanager.Follow(home, providers); err != nil || len(activated) != 2 {
+		t.Errorf("Follow(home) = %+v, %v, want global activated", activated, err)
+	}
+	if got := readFile(t, target); got != "global\n" {
+		t.Errorf("%s = %q, want %q", target, got, "global\n")
+	}
+	if _, err := os.Lstat(codexTarget); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("%s should be removed with the empty global environment, got err = %v", codexTarget, err)
+	}
+
+	// And entering it again activates the environment of the provider without deployment
+	if activated, err := contextmanager.Follow(project, providers); err != nil || len(activated) != 2 {
+		t.Errorf("Follow(project) after leaving = %+v, %v, want claude and codex activated", activated, err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/zchee/llmctxenv/fileio"
+)
+
+// ErrNothingToImport is returned by [Import] when the provider location holds no context file.
+var ErrNothingToImport = errors.New("no context file to import")
+
+// Import creates the named environment of a given provider from the context files found in the provider
+// location returned by [TargetDir]. The files are copied, so the provider location is left unchanged.
+//
+// It returns an error wrapping [ErrNothingToImport] without creating the environment if the provider
+// location holds no context file, or an error wrapping [fs.ErrExist] if the environment already exists.
+func Import(provider Provider, name string) (*Environment, error) {
+	targetDir, err := TargetDir(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file the provider CLI reads may be a symbolic link, e.g. to a dotfiles repository
+	var files []string
+	for _, file := range ContextFiles[provider] {
+		fi, err := os.Stat(filepath.Join(targetDir, file))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		if fi.Mode().IsRegular() {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: %w", targetDir, ErrNothingToImport)
+	}
+
+	env, err := CreateEnvironment(provider, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := importFile(filepath.Join(env.Dir, file), filepath.Join(targetDir, file)); err != nil {
+			return nil, errors.Join(err, os.RemoveAll(env.Dir))
+		}
+	}
+
+	return env, nil
+}
+
+// importFile copies the file at source to dest keeping its permission.
+func importFile(dest, source string) error {
+	fi, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if err := fileio.CopyFile(dest, source, fi.Mode().Perm()); err != nil {
+		return fmt.Errorf("copy %s to %s: %w", source, dest, err)
+	}
+	return nil
+}
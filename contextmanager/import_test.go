@@ -0,0 +1,91 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestImport(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	target := filepath.Join(home, ".claude", "CLAUDE.md")
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("curated"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := contextmanager.Import(contextmanager.ProviderClaudeCode, "team")
+	if err != nil {
+		t.Fatalf("Import() unexpected error: %v", err)
+	}
+	files, err := env.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(files, []string{"CLAUDE.md"}) {
+		t.Errorf("Files() = %v, want [CLAUDE.md]", files)
+	}
+	imported := filepath.Join(env.Dir, "CLAUDE.md")
+	if got := readFile(t, imported); got != "curated" {
+		t.Errorf("imported content = %q, want %q", got, "curated")
+	}
+	if fi, err := os.Stat(imported); err != nil || fi.Mode().Perm() != 0o640 {
+		t.Errorf("imported file mode = %v, %v, want %v", fi.Mode().Perm(), err, os.FileMode(0o640))
+	}
+	if got := readFile(t, target); got != "curated" {
+		t.Errorf("original content = %q, want it unchanged", got)
+	}
+
+	if _, err := contextmanager.Import(contextmanager.ProviderClaudeCode, "team"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Import() into an existing environment error = %v, want %v", err, fs.ErrExist)
+	}
+
+	// Activating the imported environment replaces the original with a managed deployment of the same content
+	d, err := contextmanager.Activate(env, contextmanager.StrategySymlink)
+	if err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	if len(d.Files) != 1 || d.Files[0].Backup == nil {
+		t.Fatalf("Activate() = %+v, want the original backed up", d)
+	}
+	if got := readFile(t, target); got != "curated" {
+		t.Errorf("deployed content = %q, want %q", got, "curated")
+	}
+}
+
+func TestImport_NothingToImport(t *testing.T) {
+	setupTestRoot(t)
+	setupTestHome(t)
+
+	if _, err := contextmanager.Import(contextmanager.ProviderGeminiCLI, "team"); !errors.Is(err, contextmanager.ErrNothingToImport) {
+		t.Errorf("Import() error = %v, want %v", err, contextmanager.ErrNothingToImport)
+	}
+	if _, err := contextmanager.LookupEnvironment(contextmanager.ProviderGeminiCLI, "team"); !errors.Is(err, contextmanager.ErrEnvironmentNotExist) {
+		t.Errorf("LookupEnvironment() error = %v, want the environment not created", err)
+	}
+}
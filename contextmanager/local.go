@@ -0,0 +1,363 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/zchee/llmctxenv/gitrepo"
+)
+
+// LocalVersionFileName is the name of the version file which selects the environments of a project directory
+// and its subdirectories.
+const LocalVersionFileName = ".llmctxenv-version"
+
+// EnvEnvironment is the environment variable which selects the environment of every provider that has it,
+// taking precedence over the version files.
+const EnvEnvironment = "LLMCTXENV_ENV"
+
+// EnvironmentVariable returns the environment variable which selects the environment of a given provider,
+// taking precedence over [EnvEnvironment] and the version files, e.g. "LLMCTXENV_GEMINI_CLI_ENV".
+func EnvironmentVariable(provider Provider) string {
+	return "LLMCTXENV_" + strings.ToUpper(strings.ReplaceAll(provider.String(), "-", "_")) + "_ENV"
+}
+
+// Selection reports the environment selected for a [Provider].
+type Selection struct {
+	Provider Provider
+
+	// Name is the name of the selected environment.
+	Name string
+
+	// Origin is the path of the version file which selects the environment. It is empty if nothing selects
+	// an environment and the [DefaultEnvironment] is used.
+	Origin string
+
+	// BranchRule is the pattern of the [BranchRule] in Origin which selects the environment, or empty if
+	// the environment is selected unconditionally.
+	BranchRule string
+
+	// Branch is the git branch matched by BranchRule.
+	Branch string
+
+	// EnvVar is the environment variable which selects the environment, or empty if the environment is
+	// selected by a version file.
+	EnvVar string
+
+	// Dir is the [LocalDir] of the project if the [ProjectEnvironment] is selected.
+	Dir string
+}
+
+// Environment returns the selected [Environment].
+//
+// It returns an error wrapping [ErrEnvironmentNotExist] if the environment does not exist.
+func (s *Selection) Environment() (*Environment, error) {
+	if s.Name == ProjectEnvironment && s.Dir != "" {
+		return &Environment{
+			Provider: s.Provider,
+			Name:     s.Name,
+			Dir:      s.Dir,
+		}, nil
+	}
+	return LookupEnvironment(s.Provider, s.Name)
+}
+
+// IsLocal reports whether the environment is selected by a [LocalVersionFileName] file.
+func (s *Selection) IsLocal() bool {
+	return s.Origin != "" && s.Origin != GlobalVersionFile()
+}
+
+// SelectionSource represents what selects the environment of a [Selection].
+type SelectionSource string
+
+const (
+	// SourceEnv means the environment is selected by the [EnvironmentVariable] of the provider or
+	// [EnvEnvironment].
+	SourceEnv SelectionSource = "env"
+
+	// SourceDefault means nothing selects an environment and the [DefaultEnvironment] is used.
+	SourceDefault SelectionSource = "default"
+
+	// SourceGlobal means the environment is selected by the [GlobalVersionFile].
+	SourceGlobal SelectionSource = "global"
+
+	// SourceLocal means the environment is selected by a [LocalVersionFileName] file.
+	SourceLocal SelectionSource = "local"
+
+	// SourceBranchRule means the environment is selected by a [BranchRule] of a version file.
+	SourceBranchRule SelectionSource = "branch-rule"
+
+	// SourceProject means no version file of the project selects an environment and the
+	// [ProjectEnvironment] is used because the project has a local system context.
+	SourceProject SelectionSource = "project"
+)
+
+// String returns a string representation of the [SelectionSource].
+func (s SelectionSource) String() string { return string(s) }
+
+// Source returns what selects the environment.
+func (s *Selection) Source() SelectionSource {
+	switch {
+	case s.EnvVar != "":
+		return SourceEnv
+	case s.BranchRule != "":
+		return SourceBranchRule
+	case s.IsLocal():
+		return SourceLocal
+	case s.Origin != "":
+		return SourceGlobal
+	case s.Dir != "":
+		return SourceProject
+	default:
+		return SourceDefault
+	}
+}
+
+// SelectOption configures [SelectEnvironment].
+type SelectOption func(*selectOptions)
+
+type selectOptions struct {
+	withoutEnvVars bool
+}
+
+// WithoutEnvVars makes [SelectEnvironment] ignore the environment variables which select environments, so
+// that the environment is selected as in a shell session which does not set them.
+func WithoutEnvVars() SelectOption {
+	return func(o *selectOptions) {
+		o.withoutEnvVars = true
+	}
+}
+
+// SelectEnvironment returns the [Selection] of a given provider in the directory dir.
+//
+// The [EnvironmentVariable] of the provider, then [EnvEnvironment] if the provider has the environment it
+// names, take precedence over the version files, so that a shell session can select its own environments.
+// The nearest [LocalVersionFileName] file in dir or its parents which selects an environment for the provider
+// takes precedence over the [GlobalVersionFile]. In each file, a [BranchRule] matching the git branch checked
+// out in dir takes precedence over the unconditional selection.
+//
+// If no version file in the project containing dir selects an environment and the [LocalDir] of the project
+// has context files, the [ProjectEnvironment] is selected in preference to the version files of the parent
+// directories of the project.
+func SelectEnvironment(provider Provider, dir string, opts ...SelectOption) (*Selection, error) {
+	var o selectOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	root, name, err := localName(dir, cfg)
+	if err != nil {
+		return nil, err
+	}
+	localDir := filepath.Join(LocalEnvironmentsDir(provider), name)
+
+	sel, err := selectEnvironment(provider, dir, root, localDir, o)
+	if err != nil {
+		return nil, err
+	}
+	if sel.Name == ProjectEnvironment {
+		sel.Dir = localDir
+	}
+	return sel, nil
+}
+
+// selectEnvironment returns the [Selection] of a given provider in the absolute directory dir of the project
+// at root whose local system context is localDir.
+func selectEnvironment(provider Provider, dir, root, localDir string, o selectOptions) (*Selection, error) {
+	if !o.withoutEnvVars {
+		if sel, err := selectFromEnv(provider); err != nil || sel != nil {
+			return sel, err
+		}
+	}
+
+	start := dir
+	branch := sync.OnceValues(func() (string, error) {
+		return currentBranch(start)
+	})
+
+	for {
+		path := filepath.Join(dir, LocalVersionFileName)
+		sel, err := selectFrom(provider, path, branch)
+		if err != nil || sel != nil {
+			return sel, err
+		}
+
+		if dir == root {
+			env := &Environment{Provider: provider, Name: ProjectEnvironment, Dir: localDir}
+			files, err := env.Files()
+			if err != nil {
+				return nil, err
+			}
+			if len(files) > 0 {
+				return &Selection{Provider: provider, Name: ProjectEnvironment}, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	sel, err := selectFrom(provider, GlobalVersionFile(), branch)
+	if err != nil || sel != nil {
+		return sel, err
+	}
+	return &Selection{Provider: provider, Name: DefaultEnvironment}, nil
+}
+
+// selectFromEnv returns the [Selection] of a given provider by the environment variables, or nil if they do
+// not select an environment for the provider.
+func selectFromEnv(provider Provider) (*Selection, error) {
+	key := EnvironmentVariable(provider)
+	if name := os.Getenv(key); name != "" {
+		if err := ValidateEnvironmentName(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		return &Selection{Provider: provider, Name: name, EnvVar: key}, nil
+	}
+
+	if name := os.Getenv(EnvEnvironment); name != "" {
+		if err := ValidateEnvironmentName(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvEnvironment, err)
+		}
+		// Unlike the variable of the provider, it applies only to the providers which have the environment
+		if _, err := LookupEnvironment(provider, name); err == nil {
+			return &Selection{Provider: provider, Name: name, EnvVar: EnvEnvironment}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// selectFrom returns the [Selection] of a given provider by the version file at path, or nil if the file does
+// not exist or does not select an environment for the provider. The git branch is read only if the file has
+// branch rules.
+func selectFrom(provider Provider, path string, currentBranch func() (string, error)) (*Selection, error) {
+	v, err := ReadVersionFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var branch string
+	if len(v.Branches) > 0 {
+		if branch, err = currentBranch(); err != nil {
+			return nil, err
+		}
+	}
+	name, rule, ok := v.Select(provider, branch)
+	if !ok {
+		return nil, nil
+	}
+
+	sel := &Selection{Provider: provider, Name: name, Origin: path}
+	if rule != nil {
+		sel.BranchRule = rule.Pattern
+		sel.Branch = branch
+	}
+	return sel, nil
+}
+
+// currentBranch returns the git branch checked out in the working tree containing dir, or empty if dir is
+// not in a working tree or HEAD is detached.
+func currentBranch(dir string) (string, error) {
+	repo, err := gitrepo.Find(dir)
+	if err != nil {
+		if errors.Is(err, gitrepo.ErrNotRepository) {
+			return "", nil
+		}
+		return "", fmt.Errorf("find git repository of %s: %w", dir, err)
+	}
+
+	branch, _, err := repo.Branch()
+	if err != nil {
+		return "", fmt.Errorf("read git branch of %s: %w", repo.WorkTree, err)
+	}
+	return branch, nil
+}
+
+// SetLocalEnvironment selects the named environment of a given provider in the [LocalVersionFileName] file
+// of the directory dir.
+func SetLocalEnvironment(provider Provider, dir, name string) error {
+	if _, err := LookupEnvironment(provider, name); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, LocalVersionFileName)
+	v, err := ReadVersionFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		v = &Version{}
+	}
+	v.Set(provider, name)
+
+	return WriteVersionFile(path, v)
+}
+
+// UnsetLocalEnvironment removes the environment of a given provider from the [LocalVersionFileName] file of
+// the directory dir. The file is removed if it selects no environment anymore.
+//
+// It reports whether the file selected an environment for the provider.
+func UnsetLocalEnvironment(provider Provider, dir string) (bool, error) {
+	path := filepath.Join(dir, LocalVersionFileName)
+	v, err := ReadVersionFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, ok := v.Lookup(provider); !ok {
+		return false, nil
+	}
+
+	v.Unset(provider)
+	if v.IsEmpty() {
+		return true, os.Remove(path)
+	}
+	return true, WriteVersionFile(path, v)
+}
+
+// workingDir returns the absolute path of the current directory.
+func workingDir() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("get current directory: %w", err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,440 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+// writeVersionFile writes the version file content into dir and returns its path.
+func writeVersionFile(t *testing.T, dir, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, contextmanager.LocalVersionFileName)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSelectEnvironment(t *testing.T) {
+	tests := map[string]struct {
+		// setup creates the version files in the project directory and returns the origin want
+		setup      func(t *testing.T, project string) string
+		provider   contextmanager.Provider
+		want       string
+		wantSource contextmanager.SelectionSource
+	}{
+		"default": {
+			setup:      func(t *testing.T, project string) string { return "" },
+			provider:   contextmanager.ProviderClaudeCode,
+			want:       contextmanager.DefaultEnvironment,
+			wantSource: contextmanager.SourceDefault,
+		},
+		"global": {
+			setup: func(t *testing.T, project string) string {
+				if err := contextmanager.SetGlobalEnvironment(contextmanager.ProviderClaudeCode, "work"); err != nil {
+					t.Fatal(err)
+				}
+				return contextmanager.GlobalVersionFile()
+			},
+			provider:   contextmanager.ProviderClaudeCode,
+			want:       "work",
+			wantSource: contextmanager.SourceGlobal,
+		},
+		"local in a parent directory": {
+			setup: func(t *testing.T, project string) string {
+				if err := contextmanager.SetGlobalEnvironment(contextmanager.ProviderClaudeCode, "work"); err != nil {
+					t.Fatal(err)
+				}
+				return writeVersionFile(t, project, "claude=review\n")
+			},
+			provider:   contextmanager.ProviderClaudeCode,
+			want:       "review",
+			wantSource: contextmanager.SourceLocal,
+		},
+		"nearest local": {
+			setup: func(t *testing.T, project string) string {
+				writeVersionFile(t, project, "claude=review\n")
+				return writeVersionFile(t, filepath.Join(project, "sub"), "claude=work\n")
+			},
+			provider:   contextmanager.ProviderClaudeCode,
+			want:       "work",
+			wantSource: contextmanager.SourceLocal,
+		},
+		"nearest local without the provider": {
+			setup: func(t *testing.T, project string) string {
+				writeVersionFile(t, filepath.Join(project, "sub"), "codex=work\n")
+				return writeVersionFile(t, project, "claude=review\n")
+			},
+			provider:   contextmanager.ProviderClaudeCode,
+			want:       "review",
+			wantSource: contextmanager.SourceLocal,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			setupTestRoot(t)
+			createEnvironment(t, contextmanager.ProviderClaudeCode, "work", nil)
+			project := t.TempDir()
+			dir := filepath.Join(project, "sub", "dir")
+			if err := os.MkdirAll(dir, 0o700); err != nil {
+				t.Fatal(err)
+			}
+			origin := tt.setup(t, project)
+
+			got, err := contextmanager.SelectEnvironment(tt.provider, dir)
+			if err != nil {
+				t.Fatalf("SelectEnvironment() unexpected error: %v", err)
+			}
+			if got.Name != tt.want || got.Origin != origin {
+				t.Errorf("SelectEnvironment() = %+v, want %s selected by %q", got, tt.want, origin)
+			}
+			if isLocal := origin != "" && origin != contextmanager.GlobalVersionFile(); got.IsLocal() != isLocal {
+				t.Errorf("IsLocal() = %t, want %t", got.IsLocal(), isLocal)
+			}
+			if got.Source() != tt.wantSource {
+				t.Errorf("Source() = %v, want %v", got.Source(), tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestSetLocalEnvironment(t *testing.T) {
+	setupTestRoot(t)
+	createEnvironment(t, contextmanager.ProviderClaudeCode, "work", nil)
+	project := t.TempDir()
+	path := writeVersionFile(t, project, "codex=global\n")
+
+	if err := contextmanager.SetLocalEnvironment(contextmanager.ProviderClaudeCode, project, "work"); err != nil {
+		t.Fatalf("SetLocalEnvironment() unexpected error: %v", err)
+	}
+	if got, want := readFile(t, path), "claude=work\ncodex=global\n"; got != want {
+		t.Errorf("version file = %q, want %q", got, want)
+	}
+
+	if err := contextmanager.SetLocalEnvironment(contextmanager.ProviderClaudeCode, project, "missing"); !errors.Is(err, contextmanager.ErrEnvironmentNotExist) {
+		t.Errorf("SetLocalEnvironment() error = %v, want %v", err, contextmanager.ErrEnvironmentNotExist)
+	}
+}
+
+func TestUnsetLocalEnvironment(t *testing.T) {
+	setupTestRoot(t)
+	project := t.TempDir()
+	path := writeVersionFile(t, project, "claude=work\ncodex=global\n")
+
+	for _, provider := range []contextmanager.Provider{contextmanager.ProviderClaudeCode, contextmanager.ProviderGeminiCLI} {
+		if _, err := contextmanager.UnsetLocalEnvironment(provider, project); err != nil {
+			t.Fatalf("UnsetLocalEnvironment(%s) unexpected error: %v", provider, err)
+		}
+	}
+	if got, want := readFile(t, path), "codex=global\n"; got != want {
+		t.Errorf("version file = %q, want %q", got, want)
+	}
+
+	ok, err := contextmanager.UnsetLocalEnvironment(contextmanager.ProviderCodex, project)
+	if err != nil || !ok {
+		t.Fatalf("UnsetLocalEnvironment() = %t, %v, want true", ok, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("empty version file %s should be removed, got err = %v", path, err)
+	}
+}
+
+func TestResolveEnvironment_Local(t *testing.T) {
+	setupTestRoot(t)
+	work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", nil)
+	project := t.TempDir()
+	if err := contextmanager.SetLocalEnvironment(contextmanager.ProviderClaudeCode, project, "work"); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(project, "pkg")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	env, err := contextmanager.ResolveEnvironment(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("ResolveEnvironment() unexpected error: %v", err)
+	}
+	if env.Name != work.Name || env.Dir != work.Dir {
+		t.Errorf("ResolveEnvironment() = %+v, want %+v", env, work)
+	}
+}
+
+func TestSelectEnvironment_Project(t *testing.T) {
+	setupTestRoot(t)
+	setupTestHome(t)
+	createEnvironment(t, contextmanager.ProviderClaudeCode, "work", nil)
+
+	parent := t.TempDir()
+	writeVersionFile(t, parent, "claude=work\n")
+	project := filepath.Join(parent, "project")
+	dir := filepath.Join(project, "pkg")
+	for _, d := range []string{filepath.Join(project, ".git"), dir} {
+		if err := os.MkdirAll(d, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The local context directory without context files does not select the project environment
+	localDir, err := contextmanager.LocalDir(contextmanager.ProviderClaudeCode, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(localDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	sel, err := contextmanager.SelectEnvironment(contextmanager.ProviderClaudeCode, dir)
+	if err != nil {
+		t.Fatalf("SelectEnvironment() unexpected error: %v", err)
+	}
+	if sel.Name != "work" {
+		t.Errorf("SelectEnvironment() = %+v, want work selected by the parent version file", sel)
+	}
+
+	if err := os.WriteFile(filepath.Join(localDir, "CLAUDE.md"), []byte("# project\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sel, err = contextmanager.SelectEnvironment(contextmanager.ProviderClaudeCode, dir)
+	if err != nil {
+		t.Fatalf("SelectEnvironment() unexpected error: %v", err)
+	}
+	if sel.Name != contextmanager.ProjectEnvironment || sel.Dir != localDir || sel.Source() != contextmanager.SourceProject {
+		t.Errorf("SelectEnvironment() = %+v, want %s in %s", sel, contextmanager.ProjectEnvironment, localDir)
+	}
+
+	// A version file in the project takes precedence over the local context
+	writeVersionFile(t, project, "claude=work\n")
+	sel, err = contextmanager.SelectEnvironment(contextmanager.ProviderClaudeCode, dir)
+	if err != nil {
+		t.Fatalf("SelectEnvironment() unexpected error: %v", err)
+	}
+	if sel.Name != "work" || sel.Source() != contextmanager.SourceLocal {
+		t.Errorf("SelectEnvironment() = %+v, want work selected by the project version file", sel)
+	}
+}
+
+func TestStatusOf_Project(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	// Two projects with their own local context
+	var projects []string
+	for _, name := range []string{"a", "b"} {
+		project := filepath.Join(home, "src", name)
+		if err := os.MkdirAll(filepath.Join(project, ".git"), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		localDir, err := contextmanager.LocalDir(contextmanager.ProviderClaudeCode, project)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(localDir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(localDir, "CLAUDE.md"), []byte("# "+name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		projects = append(projects, project)
+	}
+
+	t.Chdir(projects[0])
+	env, err := contextmanager.ResolveEnvironment(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("ResolveEnvironment() unexpected error: %v", err)
+	}
+	if env.Name != contextmanager.ProjectEnvironment {
+		t.Fatalf("ResolveEnvironment() = %+v, want %s", env, contextmanager.ProjectEnvironment)
+	}
+	if _, err := contextmanager.Activate(env, contextmanager.StrategySymlink); err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	targetDir, err := contextmanager.TargetDir(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, filepath.Join(targetDir, "CLAUDE.md")), "# a\n"; got != want {
+		t.Errorf("installed CLAUDE.md = %q, want %q", got, want)
+	}
+
+	s, err := contextmanager.StatusOf(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("StatusOf() unexpected error: %v", err)
+	}
+	if s.Drifted() {
+		t.Errorf("StatusOf() in the activated project = %+v, want not drifted", s)
+	}
+
+	// The project environment of another project is not the installed one
+	t.Chdir(projects[1])
+	s, err = contextmanager.StatusOf(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("StatusOf() unexpected error: %v", err)
+	}
+	if !s.Switched() || !s.Drifted() {
+		t.Errorf("StatusOf() in another project = %+v, want switched", s)
+	}
+}
+
+func TestSelectEnvironment_BranchRule(t *testing.T) {
+	setupTestRoot(t)
+	project := t.TempDir()
+	dir := filepath.Join(project, "sub")
+	if err := os.MkdirAll(filepath.Join(project, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	origin := writeVersionFile(t, project, "claude=review\n[branch \"release/*\"]\nclaude=release\n")
+
+	tests := []struct {
+		name     string
+		head     string // empty removes the repository
+		want     string
+		wantRule string
+	}{
+		{name: "matching branch", head: "ref: refs/heads/release/1.2\n", want: "release", wantRule: "release/*"},
+		{name: "other branch", head: "ref: refs/heads/main\n", want: "review"},
+		{name: "detached head", head: "0123456789abcdef0123456789abcdef01234567\n", want: "review"},
+		{name: "not a repository", want: "review"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head := filepath.Join(project, ".git", "HEAD")
+			if tt.head == "" {
+				if err := os.RemoveAll(filepath.Join(project, ".git")); err != nil {
+					t.Fatal(err)
+				}
+			} else if err := os.WriteFile(head, []byte(tt.head), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := contextmanager.SelectEnvironment(contextmanager.ProviderClaudeCode, dir)
+			if err != nil {
+				t.Fatalf("SelectEnvironment() unexpected error: %v", err)
+			}
+			if got.Name != tt.want || got.Origin != origin || got.BranchRule != tt.wantRule || (got.Source() == contextmanager.SourceBranchRule) != (tt.wantRule != "") {
+				t.Errorf("SelectEnvironment() = %+v, want %s selected by rule %q of %s", got, tt.want, tt.wantRule, origin)
+			}
+			if tt.wantRule != "" && got.Branch != "release/1.2" {
+				t.Errorf("SelectEnvironment().Branch = %q, want %q", got.Branch, "release/1.2")
+			}
+		})
+	}
+}
+
+func TestEnvironmentVariable(t *testing.T) {
+	t.Parallel()
+
+	for provider, want := range map[contextmanager.Provider]string{
+		contextmanager.ProviderClaudeCode: "LLMCTXENV_CLAUDE_ENV",
+		contextmanager.ProviderGeminiCLI:  "LLMCTXENV_GEMINI_CLI_ENV",
+	} {
+		if got := contextmanager.EnvironmentVariable(provider); got != want {
+			t.Errorf("EnvironmentVariable(%v) = %q, want %q", provider, got, want)
+		}
+	}
+}
+
+func TestSelectEnvironment_Env(t *testing.T) {
+	tests := map[string]struct {
+		providerEnv string
+		env         string
+		provider    contextmanager.Provider
+		want        string
+		wantEnvVar  string
+		wantErr     bool
+	}{
+		"provider variable": {
+			providerEnv: "work",
+			env:         "review",
+			provider:    contextmanager.ProviderClaudeCode,
+			want:        "work",
+			wantEnvVar:  "LLMCTXENV_CLAUDE_ENV",
+		},
+		"provider variable of missing environment": {
+			providerEnv: "missing",
+			provider:    contextmanager.ProviderClaudeCode,
+			want:        "missing",
+			wantEnvVar:  "LLMCTXENV_CLAUDE_ENV",
+		},
+		"all providers": {
+			env:        "review",
+			provider:   contextmanager.ProviderClaudeCode,
+			want:       "review",
+			wantEnvVar: "LLMCTXENV_ENV",
+		},
+		"all providers without the environment": {
+			env:      "work",
+			provider: contextmanager.ProviderCodex,
+			want:     "local",
+		},
+		"none": {
+			provider: contextmanager.ProviderClaudeCode,
+			want:     "local",
+		},
+		"invalid name": {
+			env:      "../work",
+			provider: contextmanager.ProviderClaudeCode,
+			wantErr:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			setupTestRoot(t)
+			createEnvironment(t, contextmanager.ProviderClaudeCode, "work", nil)
+			createEnvironment(t, contextmanager.ProviderClaudeCode, "review", nil)
+			project := t.TempDir()
+			writeVersionFile(t, project, "claude=local\ncodex=local\n")
+			t.Setenv(contextmanager.EnvironmentVariable(tt.provider), tt.providerEnv)
+			t.Setenv(contextmanager.EnvEnvironment, tt.env)
+
+			got, err := contextmanager.SelectEnvironment(tt.provider, project)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SelectEnvironment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Name != tt.want || got.EnvVar != tt.wantEnvVar {
+				t.Errorf("SelectEnvironment() = %+v, want %s selected by %q", got, tt.want, tt.wantEnvVar)
+			}
+			if (got.Source() == contextmanager.SourceEnv) != (tt.wantEnvVar != "") || got.IsLocal() == (tt.wantEnvVar != "") {
+				t.Errorf("Source() = %v, IsLocal() = %t", got.Source(), got.IsLocal())
+			}
+
+			got, err = contextmanager.SelectEnvironment(tt.provider, project, contextmanager.WithoutEnvVars())
+			if err != nil {
+				t.Fatalf("SelectEnvironment(WithoutEnvVars()) unexpected error: %v", err)
+			}
+			if got.Name != "local" || got.EnvVar != "" {
+				t.Errorf("SelectEnvironment(WithoutEnvVars()) = %+v, want local selected by the version file", got)
+			}
+		})
+	}
+}
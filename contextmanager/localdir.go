@@ -0,0 +1,353 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zchee/llmctxenv/fileio"
+)
+
+// EncodeProjectPath returns the name of the directory in [LocalEnvironmentsDir] for the project at the
+// absolute path.
+//
+// The encoding is lossless, so [DecodeProjectPath] recovers the path. A path in the user home directory is
+// encoded relative to it, any other path is encoded as a whole and so starts with '-':
+//
+//   - '/' is encoded as '-'
+//   - an upper case letter is encoded as '!' followed by the lower case letter, which keeps the names
+//     distinct on case insensitive file systems
+//   - '!' and '-' are encoded as "!!" and "!-"
+//   - '~', '@' and the characters which are not portable in file names are encoded as "!~" followed by two
+//     hexadecimal digits
+//
+// The user home directory itself is encoded as "~". Names starting with '@' are left for the projects
+// identified by [EncodeRemoteKey].
+func EncodeProjectPath(path string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get current user home directory: %w", err)
+	}
+	return encodeProjectPath(path, home), nil
+}
+
+func encodeProjectPath(path, home string) string {
+	path = filepath.Clean(path)
+	if path == home {
+		return "~"
+	}
+	if rel, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		path = rel
+	}
+	return escapeName(filepath.ToSlash(path))
+}
+
+// escapeName returns the slash separated path s encoded as a file name as described in [EncodeProjectPath].
+func escapeName(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case c == '/':
+			b.WriteByte('-')
+		case 'A' <= c && c <= 'Z':
+			b.WriteByte('!')
+			b.WriteByte(c + 'a' - 'A')
+		case c == '!', c == '-':
+			b.WriteByte('!')
+			b.WriteByte(c)
+		case c == '~', c == '@', c < 0x20, c == 0x7f, strings.IndexByte(`\:*?"<>|`, c) >= 0:
+			fmt.Fprintf(&b, "!~%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// DecodeProjectPath returns the absolute path of the project whose local system context is stored in the
+// directory name encoded by [EncodeProjectPath].
+func DecodeProjectPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get current user home directory: %w", err)
+	}
+	return decodeProjectPath(name, home)
+}
+
+func decodeProjectPath(name, home string) (string, error) {
+	if name == "~" {
+		return home, nil
+	}
+
+	s, err := unescapeName(name)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.FromSlash(s)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(home, path)
+	}
+	return path, nil
+}
+
+// unescapeName returns the slash separated path encoded by [escapeName].
+func unescapeName(name string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch c {
+		case '-':
+			b.WriteByte('/')
+			continue
+		case '!':
+			// decoded below
+		default:
+			if 'A' <= c && c <= 'Z' || c == '~' || c == '@' {
+				return "", fmt.Errorf("invalid project directory name %q: unescaped %q", name, c)
+			}
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i == len(name) {
+			return "", fmt.Errorf("invalid project directory name %q: trailing '!'", name)
+		}
+		switch c := name[i]; {
+		case 'a' <= c && c <= 'z':
+			b.WriteByte(c - 'a' + 'A')
+		case c == '!', c == '-':
+			b.WriteByte(c)
+		case c == '~' && i+2 < len(name):
+			x, err := strconv.ParseUint(name[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid project directory name %q: %w", name, err)
+			}
+			b.WriteByte(byte(x))
+			i += 2
+		default:
+			return "", fmt.Errorf("invalid project directory name %q: invalid escape at offset %d", name, i-1)
+		}
+	}
+
+	if b.Len() == 0 {
+		return "", fmt.Errorf("invalid project directory name %q", name)
+	}
+	return b.String(), nil
+}
+
+// EncodeRemoteKey returns the name of the directory in [LocalEnvironmentsDir] for the project identified
+// by the remote key, the normalized remote URL of its repository as returned by [gitrepo.NormalizeURL]
+// optionally followed by the slash separated path of the project in the repository.
+//
+// The name is '@' followed by the key encoded as described in [EncodeProjectPath], so it never collides
+// with the name of a project path. [DecodeRemoteKey] recovers the key.
+func EncodeRemoteKey(key string) string {
+	return "@" + escapeName(key)
+}
+
+// DecodeRemoteKey returns the remote key of the project whose local system context is stored in the
+// directory name encoded by [EncodeRemoteKey].
+func DecodeRemoteKey(name string) (string, error) {
+	rest, ok := strings.CutPrefix(name, "@")
+	if !ok {
+		return "", fmt.Errorf("invalid remote project directory name %q", name)
+	}
+	return unescapeName(rest)
+}
+
+// dirnameReplacer is the legacy encoding of the project paths in [LocalEnvironmentsDir]. It is not
+// reversible and maps different paths such as "a.b" and "a/b" to the same name.
+var dirnameReplacer = strings.NewReplacer(
+	".", "-",
+	string(filepath.Separator), "-",
+)
+
+// localLayoutFile returns the path of the file which records that the names in each
+// [LocalEnvironmentsDir] are encoded by [EncodeProjectPath].
+func localLayoutFile() string {
+	return filepath.Join(LLMCtxEnvRoot, "local", ".layout")
+}
+
+// LegacyLocalDir returns the directory path which holds the local system contexts of a given provider whose
+// project could not be recovered from the legacy directory name.
+func LegacyLocalDir(provider Provider) string {
+	return filepath.Join(LLMCtxEnvRoot, "local-legacy", provider.String())
+}
+
+// LocalMigration represents the migration of a local system context directory from the legacy layout.
+type LocalMigration struct {
+	Provider Provider
+	From     string
+	To       string
+
+	// Project is the path of the project recovered from the legacy name, or empty if the name matches
+	// no project or more than one project and the directory was moved to [LegacyLocalDir].
+	Project string
+}
+
+// ErrLegacyLayout is returned when the local system context directories are named by the legacy encoding
+// and have not been migrated by [MigrateLocalDirs] yet.
+var ErrLegacyLayout = errors.New("local context directories use the legacy layout")
+
+// checkLocalLayout returns [ErrLegacyLayout] if there are local system context directories whose layout
+// has not been recorded by [MigrateLocalDirs].
+func checkLocalLayout() error {
+	if _, err := os.Stat(localLayoutFile()); err == nil {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Dir(localLayoutFile())); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return ErrLegacyLayout
+}
+
+// MigrateLocalDirs renames the local system context directories named by the legacy encoding to the names
+// encoded by [EncodeProjectPath].
+//
+// The legacy encoding is not reversible, so the project of a directory is recovered by searching the file
+// system for the single existing directory whose legacy name matches. The migration runs once; it is a
+// no-op once the layout has been recorded in the local directory, which is done immediately if there is
+// no local directory yet.
+//
+// The lookups of the local system contexts never migrate them, so it must be run before the directories are
+// created or changed.
+func MigrateLocalDirs() ([]LocalMigration, error) {
+	localDir := filepath.Dir(localLayoutFile())
+	if _, err := os.Stat(localLayoutFile()); err == nil {
+		return nil, nil
+	}
+	if _, err := os.Stat(localDir); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		// Nothing to migrate, record the layout for the directories created from now on
+		if err := os.MkdirAll(localDir, 0o700); err != nil {
+			return nil, fmt.Errorf("mkdir all %s path: %w", localDir, err)
+		}
+		return nil, os.WriteFile(localLayoutFile(), []byte("2\n"), 0o644)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("get current user home directory: %w", err)
+	}
+
+	var migrations []LocalMigration
+	for _, provider := range Providers() {
+		dir := LocalEnvironmentsDir(provider)
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return migrations, fmt.Errorf("ReadDir %s: %w", dir, err)
+		}
+
+		for _, ent := range ents {
+			if !ent.IsDir() {
+				continue
+			}
+			m := LocalMigration{
+				Provider: provider,
+				From:     filepath.Join(dir, ent.Name()),
+			}
+			if project, ok := resolveLegacyName(ent.Name(), home); ok {
+				m.Project = project
+				m.To = filepath.Join(dir, encodeProjectPath(project, home))
+			}
+			if m.To == m.From {
+				continue
+			}
+			if m.To == "" || fileio.IsExist(m.To) {
+				m.Project = ""
+				m.To = filepath.Join(LegacyLocalDir(provider), ent.Name())
+			}
+
+			if err := os.MkdirAll(filepath.Dir(m.To), 0o700); err != nil {
+				return migrations, fmt.Errorf("mkdir all %s path: %w", filepath.Dir(m.To), err)
+			}
+			if err := os.Rename(m.From, m.To); err != nil {
+				return migrations, fmt.Errorf("rename %s to %s: %w", m.From, m.To, err)
+			}
+			migrations = append(migrations, m)
+		}
+	}
+
+	if err := os.WriteFile(localLayoutFile(), []byte("2\n"), 0o644); err != nil {
+		return migrations, err
+	}
+	return migrations, nil
+}
+
+// resolveLegacyName returns the path of the single existing directory whose legacy name, relative to home
+// if the path is in home, is name.
+//
+// The directories are searched from left to right: in each directory, only the subdirectories whose legacy
+// name is the next part of name are followed, so that the search is bounded by the existing directories.
+func resolveLegacyName(name, home string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+
+	var matches []string
+	var walk func(dir, rest string)
+	walk = func(dir, rest string) {
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, ent := range ents {
+			if len(matches) > 1 {
+				return
+			}
+			// each '-' was either a separator, a '.' or a '-' in the original path
+			elem := dirnameReplacer.Replace(ent.Name())
+			if rest != elem && !strings.HasPrefix(rest, elem+"-") {
+				continue
+			}
+			path := filepath.Join(dir, ent.Name())
+			if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+				continue
+			}
+			if rest == elem {
+				matches = append(matches, path)
+				continue
+			}
+			walk(path, rest[len(elem)+1:])
+		}
+	}
+	walk(home, name)
+	if rest, ok := strings.CutPrefix(name, "-"); ok {
+		// a path outside of home, which started with a separator
+		walk(string(filepath.Separator), rest)
+	}
+
+	if len(matches) != 1 {
+		return "", false
+	}
+	return matches[0], true
+}
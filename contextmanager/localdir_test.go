@@ -0,0 +1,217 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestEncodeProjectPath(t *testing.T) {
+	home := setupTestHome(t)
+
+	tests := map[string]struct {
+		path string
+		want string
+	}{
+		"in home": {
+			path: filepath.Join(home, "src", "github.com", "zchee", "llmctxenv"),
+			want: "src-github.com-zchee-llmctxenv",
+		},
+		"dot": {
+			path: filepath.Join(home, "src", "a.b"),
+			want: "src-a.b",
+		},
+		"separator": {
+			path: filepath.Join(home, "src", "a", "b"),
+			want: "src-a-b",
+		},
+		"hyphen": {
+			path: filepath.Join(home, "src", "a-b"),
+			want: "src-a!-b",
+		},
+		"upper case": {
+			path: filepath.Join(home, "MyProject", "SubDir"),
+			want: "!my!project-!sub!dir",
+		},
+		"escape": {
+			path: filepath.Join(home, "a!b~c:d"),
+			want: "a!!b!~7ec!~3ad",
+		},
+		"at sign": {
+			path: filepath.Join(home, "@scope", "pkg"),
+			want: "!~40scope-pkg",
+		},
+		"home": {
+			path: home,
+			want: "~",
+		},
+		"outside home": {
+			path: "/tmp/project",
+			want: "-tmp-project",
+		},
+		"root": {
+			path: "/",
+			want: "-",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := contextmanager.EncodeProjectPath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("EncodeProjectPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+
+			path, err := contextmanager.DecodeProjectPath(got)
+			if err != nil {
+				t.Fatalf("DecodeProjectPath(%q) error = %v", got, err)
+			}
+			if path != tt.path {
+				t.Errorf("DecodeProjectPath(%q) = %q, want %q", got, path, tt.path)
+			}
+		})
+	}
+}
+
+func TestDecodeProjectPath_Invalid(t *testing.T) {
+	setupTestHome(t)
+
+	for _, name := range []string{"", "a!", "!A", "a!~zz", "a!~7", "Upper", "a~b", "@remote"} {
+		if path, err := contextmanager.DecodeProjectPath(name); err == nil {
+			t.Errorf("DecodeProjectPath(%q) = %q, want error", name, path)
+		}
+	}
+}
+
+// recordLocalLayout records the layout of the local directories in a new root, as the commands do by
+// running [contextmanager.MigrateLocalDirs] first.
+func recordLocalLayout(t *testing.T) {
+	t.Helper()
+
+	if _, err := contextmanager.MigrateLocalDirs(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateLocalDirs(t *testing.T) {
+	root := setupTestRoot(t)
+	home := setupTestHome(t)
+
+	for _, dir := range []string{"src/a.b", "src/my-app/cmd", "src/x.y", "src/x/y", ".config/nvim"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	provider := contextmanager.ProviderClaudeCode
+	localDir := filepath.Join(root, "local", provider.String())
+	// a name with many separators, which is resolved by the existing directories only
+	long := strings.Repeat("src-", 40) + "x"
+	for _, name := range []string{"src-a-b", "src-my-app-cmd", "src-x-y", "-config-nvim", "gone", long} {
+		if err := os.MkdirAll(filepath.Join(localDir, name), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(localDir, name, "CLAUDE.md"), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := contextmanager.Projects(); !errors.Is(err, contextmanager.ErrLegacyLayout) {
+		t.Errorf("Projects() before the migration error = %v, want %v", err, contextmanager.ErrLegacyLayout)
+	}
+
+	migrations, err := contextmanager.MigrateLocalDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{ // legacy name to migrated path
+		"src-a-b":        filepath.Join(localDir, "src-a.b"),
+		"src-my-app-cmd": filepath.Join(localDir, "src-my!-app-cmd"),
+		"-config-nvim":   filepath.Join(localDir, ".config-nvim"),
+		"src-x-y":        filepath.Join(root, "local-legacy", provider.String(), "src-x-y"),
+		"gone":           filepath.Join(root, "local-legacy", provider.String(), "gone"),
+		long:             filepath.Join(root, "local-legacy", provider.String(), long),
+	}
+	if len(migrations) != len(want) {
+		t.Errorf("MigrateLocalDirs() = %+v, want %d migrations", migrations, len(want))
+	}
+	for _, m := range migrations {
+		name := filepath.Base(m.From)
+		if m.To != want[name] {
+			t.Errorf("%s migrated to %q, want %q", name, m.To, want[name])
+		}
+		if got := readFile(t, filepath.Join(m.To, "CLAUDE.md")); got != name {
+			t.Errorf("%s content = %q, want %q", m.To, got, name)
+		}
+		if (m.Project == "") != (filepath.Dir(m.To) != localDir) {
+			t.Errorf("%s project = %q", name, m.Project)
+		}
+	}
+
+	// the layout is recorded, so an existing directory is never renamed again
+	if err := os.MkdirAll(filepath.Join(localDir, "src-x-y"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if migrations, err := contextmanager.MigrateLocalDirs(); err != nil || len(migrations) != 0 {
+		t.Errorf("MigrateLocalDirs() again = %+v, %v, want no migration", migrations, err)
+	}
+}
+
+func TestLocalDir_NoCollision(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	provider := contextmanager.ProviderCodex
+	a, err := contextmanager.LocalDir(provider, filepath.Join(home, "src", "a.b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := contextmanager.LocalDir(provider, filepath.Join(home, "src", "a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Errorf("LocalDir() of different projects = %q", a)
+	}
+}
+
+func TestEncodeRemoteKey(t *testing.T) {
+	t.Parallel()
+
+	for key, want := range map[string]string{
+		"github.com/zchee/llmctxenv":       "@github.com-zchee-llmctxenv",
+		"github.com/zchee/llmctxenv/tools": "@github.com-zchee-llmctxenv-tools",
+		"example.com/My-Repo":              "@example.com-!my!-!repo",
+		"/srv/git/repo":                    "@-srv-git-repo",
+	} {
+		got := contextmanager.EncodeRemoteKey(key)
+		if got != want {
+			t.Errorf("EncodeRemoteKey(%q) = %q, want %q", key, got, want)
+		}
+		if decoded, err := contextmanager.DecodeRemoteKey(got); err != nil || decoded != key {
+			t.Errorf("DecodeRemoteKey(%q) = %q, %v, want %q", got, decoded, err, key)
+		}
+	}
+}
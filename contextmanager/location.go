@@ -0,0 +1,89 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Location describes the directory where a provider CLI reads its global context files.
+type Location struct {
+	// Dir is the directory path relative to the user home directory.
+	Dir string
+
+	// XDGConfig reports whether Dir is placed under $XDG_CONFIG_HOME instead of "~/.config" when the
+	// variable is set.
+	XDGConfig bool
+
+	// HomeEnv is the environment variable which overrides the directory, if the provider CLI has one. It is
+	// used to redirect the CLI to the home materialized by [MaterializeHome].
+	HomeEnv string
+}
+
+// Locations maps each [Provider] to the [Location] where the provider CLI reads its global context files.
+var Locations = map[Provider]Location{
+	ProviderClaudeCode: {
+		Dir:     ".claude",
+		HomeEnv: "CLAUDE_CONFIG_DIR",
+	},
+	ProviderGeminiCLI: {
+		Dir: ".gemini",
+	},
+	ProviderQwenCLI: {
+		Dir: ".qwen",
+	},
+	ProviderCodex: {
+		Dir:     ".codex",
+		HomeEnv: "CODEX_HOME",
+	},
+	ProviderOpenCode: {
+		Dir:       filepath.Join(".config", "opencode"),
+		XDGConfig: true,
+	},
+	ProviderGoose: {
+		Dir:       filepath.Join(".config", "goose"),
+		XDGConfig: true,
+	},
+	ProviderCrush: {
+		Dir:       filepath.Join(".config", "crush"),
+		XDGConfig: true,
+	},
+}
+
+// TargetDir returns the directory path where the CLI of a given provider reads its global context files.
+func TargetDir(provider Provider) (string, error) {
+	loc, ok := Locations[provider]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownProvider, provider)
+	}
+
+	if loc.XDGConfig {
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(xdg) {
+			return filepath.Join(xdg, strings.TrimPrefix(loc.Dir, ".config"+string(filepath.Separator))), nil
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get current user home directory: %w", err)
+	}
+
+	return filepath.Join(home, loc.Dir), nil
+}
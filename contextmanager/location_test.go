@@ -0,0 +1,103 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestLocations(t *testing.T) {
+	t.Parallel()
+
+	for _, provider := range contextmanager.Providers() {
+		t.Run(provider.String(), func(t *testing.T) {
+			t.Parallel()
+
+			loc, ok := contextmanager.Locations[provider]
+			if !ok {
+				t.Fatalf("Provider %s missing from Locations mapping", provider)
+			}
+			if loc.Dir == "" || filepath.IsAbs(loc.Dir) {
+				t.Errorf("Locations[%s].Dir = %q, want relative path", provider, loc.Dir)
+			}
+		})
+	}
+}
+
+func TestTargetDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := map[string]struct {
+		provider      contextmanager.Provider
+		xdgConfigHome string
+		want          string
+		wantErr       bool
+	}{
+		"claude": {
+			provider: contextmanager.ProviderClaudeCode,
+			want:     filepath.Join(home, ".claude"),
+		},
+		"gemini-cli": {
+			provider: contextmanager.ProviderGeminiCLI,
+			want:     filepath.Join(home, ".gemini"),
+		},
+		"codex": {
+			provider: contextmanager.ProviderCodex,
+			want:     filepath.Join(home, ".codex"),
+		},
+		"opencode without XDG_CONFIG_HOME": {
+			provider: contextmanager.ProviderOpenCode,
+			want:     filepath.Join(home, ".config", "opencode"),
+		},
+		"opencode with XDG_CONFIG_HOME": {
+			provider:      contextmanager.ProviderOpenCode,
+			xdgConfigHome: "/xdg/config",
+			want:          filepath.Join("/xdg/config", "opencode"),
+		},
+		"goose with relative XDG_CONFIG_HOME": {
+			provider:      contextmanager.ProviderGoose,
+			xdgConfigHome: "relative",
+			want:          filepath.Join(home, ".config", "goose"),
+		},
+		"claude ignores XDG_CONFIG_HOME": {
+			provider:      contextmanager.ProviderClaudeCode,
+			xdgConfigHome: "/xdg/config",
+			want:          filepath.Join(home, ".claude"),
+		},
+		"unknown provider": {
+			provider: contextmanager.Provider("unknown"),
+			wantErr:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", tt.xdgConfigHome)
+
+			got, err := contextmanager.TargetDir(tt.provider)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TargetDir(%v) error = %v, wantErr %v", tt.provider, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("TargetDir(%v) = %v, want %v", tt.provider, got, tt.want)
+			}
+		})
+	}
+}
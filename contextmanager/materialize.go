@@ -0,0 +1,160 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/zchee/llmctxenv/fileio"
+)
+
+// materializeTime returns the modification time of the materialized files, which is taken from the
+// SOURCE_DATE_EPOCH environment variable of reproducible builds, or the Unix epoch.
+func materializeTime() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Unix(0, 0).UTC(), nil
+	}
+	sec, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("SOURCE_DATE_EPOCH: %w", err)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// Materialize writes the files of the [Environment] into the directory dir laid out as the home directory of
+// a user, at the [Location] of the provider relative to the home (e.g. "dir/.claude/CLAUDE.md"), and returns
+// the paths of the written files in lexical order.
+//
+// The output is deterministic so that it can be baked into container images: the files are copied with
+// [fileio.CopyDir] or rendered from the [CanonicalFile], their modes are normalized to 0644, or 0755 for
+// directories and executables, and their modification times are set to SOURCE_DATE_EPOCH, or the Unix epoch.
+// The files of the environment replace the ones of the same name, the other files in the directory are kept.
+func Materialize(env *Environment, dir string) ([]string, error) {
+	loc, ok := Locations[env.Provider]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownProvider, env.Provider)
+	}
+	mtime, err := materializeTime()
+	if err != nil {
+		return nil, err
+	}
+
+	dest := filepath.Join(dir, loc.Dir)
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir all %s path: %w", dest, err)
+	}
+
+	// Copy into a temporary directory next to the destination, so that the files are replaced only once all
+	// of them have been copied
+	tmp, err := os.MkdirTemp(dest, ".llmctxenv-materialize-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	if fileio.IsExist(env.Dir) {
+		if err := fileio.CopyDir(env.Dir, tmp); err != nil {
+			return nil, fmt.Errorf("copy %s environment %s: %w", env.Provider, env.Name, err)
+		}
+	}
+	if env.IsRendered() {
+		// The rendered context files replace the overrides copied from the environment
+		sourceDir, err := env.SourceDir()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range ContextFiles[env.Provider] {
+			dest := filepath.Join(tmp, name)
+			if err := os.Remove(dest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("remove %s: %w", dest, err)
+			}
+			if err := fileio.CopyFile(dest, filepath.Join(sourceDir, name), 0o644); err != nil {
+				return nil, fmt.Errorf("copy rendered %s: %w", name, err)
+			}
+		}
+	}
+
+	// WalkDir visits the entries in lexical order
+	var files, dirs []string
+	err = filepath.WalkDir(tmp, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(tmp, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs = append(dirs, rel)
+			return os.Chmod(path, 0o755)
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		perm := fs.FileMode(0o644)
+		if fi.Mode().Perm()&0o111 != 0 {
+			perm = 0o755
+		}
+		if err := os.Chmod(path, perm); err != nil {
+			return err
+		}
+		files = append(files, filepath.Join(dest, rel))
+		return os.Chtimes(path, mtime, mtime)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("normalize %s: %w", tmp, err)
+	}
+
+	ents, err := os.ReadDir(tmp)
+	if err != nil {
+		return nil, fmt.Errorf("ReadDir %s: %w", tmp, err)
+	}
+	for _, ent := range ents {
+		target := filepath.Join(dest, ent.Name())
+		if err := os.RemoveAll(target); err != nil {
+			return nil, fmt.Errorf("remove %s: %w", target, err)
+		}
+		if err := os.Rename(filepath.Join(tmp, ent.Name()), target); err != nil {
+			return nil, fmt.Errorf("rename %s: %w", target, err)
+		}
+	}
+
+	if err := os.Remove(tmp); err != nil {
+		return nil, err
+	}
+
+	// The directories are done last, as replacing their entries has changed their times
+	for _, rel := range dirs[1:] {
+		if err := os.Chtimes(filepath.Join(dest, rel), mtime, mtime); err != nil {
+			return nil, err
+		}
+	}
+	for path := dest; path != filepath.Clean(dir); path = filepath.Dir(path) {
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestMaterialize(t *testing.T) {
+	tests := map[string]struct {
+		provider  contextmanager.Provider
+		files     map[string]string
+		epoch     string
+		wantDir   string
+		wantFiles []string
+		wantMtime time.Time
+		wantErr   bool
+	}{
+		"claude": {
+			provider:  contextmanager.ProviderClaudeCode,
+			files:     map[string]string{"CLAUDE.md": "review", "commands/review.md": "command"},
+			wantDir:   ".claude",
+			wantFiles: []string{"CLAUDE.md", "commands/review.md"},
+			wantMtime: time.Unix(0, 0),
+		},
+		"opencode": {
+			provider:  contextmanager.ProviderOpenCode,
+			files:     map[string]string{"AGENTS.md": "review"},
+			wantDir:   ".config/opencode",
+			wantFiles: []string{"AGENTS.md"},
+			wantMtime: time.Unix(0, 0),
+		},
+		"SOURCE_DATE_EPOCH": {
+			provider:  contextmanager.ProviderCodex,
+			files:     map[string]string{"AGENTS.md": "review"},
+			epoch:     "1700000000",
+			wantDir:   ".codex",
+			wantFiles: []string{"AGENTS.md"},
+			wantMtime: time.Unix(1700000000, 0),
+		},
+		"invalid SOURCE_DATE_EPOCH": {
+			provider: contextmanager.ProviderCodex,
+			epoch:    "yesterday",
+			wantErr:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			setupTestRoot(t)
+			t.Setenv("SOURCE_DATE_EPOCH", tt.epoch)
+
+			env := createEnvironment(t, tt.provider, "review", nil)
+			for file, content := range tt.files {
+				path := filepath.Join(env.Dir, file)
+				if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			into := t.TempDir()
+			dest := filepath.Join(into, tt.wantDir)
+			if !tt.wantErr {
+				// A stale file of the environment is replaced, the others are kept
+				if err := os.MkdirAll(dest, 0o700); err != nil {
+					t.Fatal(err)
+				}
+				for file, content := range map[string]string{tt.wantFiles[0]: "stale", "settings.json": "{}"} {
+					if err := os.WriteFile(filepath.Join(dest, file), []byte(content), 0o600); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			got, err := contextmanager.Materialize(env, into)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Materialize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var want []string
+			for _, file := range tt.wantFiles {
+				want = append(want, filepath.Join(dest, file))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Materialize() = %v, want %v", got, want)
+			}
+			for _, path := range want {
+				rel, _ := filepath.Rel(dest, path)
+				if content, want := readFile(t, path), tt.files[filepath.ToSlash(rel)]; content != want {
+					t.Errorf("%s = %q, want %q", path, content, want)
+				}
+				fi, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if fi.Mode().Perm() != 0o644 || !fi.ModTime().Equal(tt.wantMtime) {
+					t.Errorf("%s mode = %v, mtime = %v, want 0644 and %v", path, fi.Mode().Perm(), fi.ModTime(), tt.wantMtime)
+				}
+			}
+			if content := readFile(t, filepath.Join(dest, "settings.json")); content != "{}" {
+				t.Errorf("settings.json = %q, want kept", content)
+			}
+
+			// The directories up to the destination have the same times, and no temporary file is left
+			for path := dest; path != into; path = filepath.Dir(path) {
+				fi, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !fi.ModTime().Equal(tt.wantMtime) {
+					t.Errorf("%s mtime = %v, want %v", path, fi.ModTime(), tt.wantMtime)
+				}
+			}
+			ents, err := os.ReadDir(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, ent := range ents {
+				if strings.HasPrefix(ent.Name(), ".llmctxenv-") {
+					t.Errorf("temporary %s is left in %s", ent.Name(), dest)
+				}
+			}
+		})
+	}
+}
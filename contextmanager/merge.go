@@ -0,0 +1,206 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/zchee/llmctxenv/fileio"
+	"github.com/zchee/llmctxenv/merge"
+)
+
+var (
+	// ErrConflictMarker is returned by [Resolve] when a managed file still holds conflict markers.
+	ErrConflictMarker = errors.New("conflict marker remains")
+
+	// ErrNoMergeBase is returned by [Sync] when the content installed by [Activate], which is the base of the
+	// three-way merge, is missing from [BaseDir].
+	ErrNoMergeBase = errors.New("the installed content to merge from is missing")
+)
+
+// BaseDir returns the directory path that holds the content of the installed files at the time of the
+// installation, which is the base of the three-way merge of an installed file and its managed source.
+func BaseDir() string {
+	return filepath.Join(LLMCtxEnvRoot, "bases")
+}
+
+// snapshot returns the [fileio.HashFile] digest of the file at path and keeps its content in [BaseDir].
+func snapshot(path string) (string, error) {
+	digest, err := fileio.HashFile(path)
+	if err != nil {
+		return "", fmt.Errorf("hash %s: %w", path, err)
+	}
+
+	dest := filepath.Join(BaseDir(), digest)
+	if fileio.IsExist(dest) {
+		return digest, nil
+	}
+	if err := os.MkdirAll(BaseDir(), 0o700); err != nil {
+		return "", fmt.Errorf("mkdir all %s path: %w", BaseDir(), err)
+	}
+	if err := writeFileAtomic(dest, path, 0o600); err != nil {
+		return "", fmt.Errorf("snapshot %s: %w", path, err)
+	}
+
+	return digest, nil
+}
+
+// writeFileAtomic copies the file at source to dest with the permission through a temporary file, so that
+// dest never holds a partial content.
+func writeFileAtomic(dest, source string, perm fs.FileMode) error {
+	tmp := stagingPath(dest)
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove %s: %w", tmp, err)
+	}
+	if err := fileio.CopyFile(tmp, source, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// base returns the content of the installed file of f at the time of the installation.
+//
+// It returns an error wrapping [ErrNoMergeBase] if the content is missing, as merging without the base would
+// report every change as a conflict.
+func (f *DeployedFile) base() ([]byte, error) {
+	base, err := os.ReadFile(filepath.Join(BaseDir(), f.Digest))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w, activate the environment again", f.Target, ErrNoMergeBase)
+		}
+		return nil, err
+	}
+	return base, nil
+}
+
+// merge merges the changes made to the installed file of f into its managed source using the content
+// recorded at the installation as the base, and reinstalls the merged source with the strategy.
+//
+// If the changes conflict, the merged source holds conflict markers, f is marked as [DeployedFile.Conflict]
+// and the installed file is left unchanged until [Resolve]. It returns the number of conflicts.
+func (f *DeployedFile) merge(strategy Strategy) (int, error) {
+	base, err := f.base()
+	if err != nil {
+		return 0, err
+	}
+	ours, err := os.ReadFile(f.Source)
+	if err != nil {
+		return 0, err
+	}
+	theirs, err := os.ReadFile(f.Target)
+	if err != nil {
+		return 0, err
+	}
+
+	res := merge.Merge(string(base), string(ours), string(theirs), merge.Labels{Ours: f.Source, Theirs: f.Target})
+
+	fi, err := os.Stat(f.Source)
+	if err != nil {
+		return 0, err
+	}
+	tmp := stagingPath(f.Source)
+	if err := os.WriteFile(tmp, []byte(res.Text), fi.Mode().Perm()); err != nil {
+		os.Remove(tmp)
+		return 0, fmt.Errorf("write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, f.Source); err != nil {
+		os.Remove(tmp)
+		return 0, fmt.Errorf("write %s: %w", f.Source, err)
+	}
+
+	if res.Conflicts > 0 {
+		// The installed content is the base of the next merge
+		if f.Digest, err = snapshot(f.Target); err != nil {
+			return 0, err
+		}
+		f.Conflict = true
+		return res.Conflicts, nil
+	}
+
+	f.Conflict = false
+	return 0, f.reinstall(strategy)
+}
+
+// ResolvedFile reports a file resolved by [Resolve].
+type ResolvedFile struct {
+	Source string
+	Target string
+
+	// Conflicts is the number of conflicts left by merging the changes which were made to the installed
+	// file after the conflict.
+	Conflicts int
+}
+
+// Resolve accepts the managed sources of the conflicting files of a given provider, from which the conflict
+// markers written by [Sync] have been removed, and installs them.
+//
+// It returns an error wrapping [ErrConflictMarker] without changing anything if a managed source still holds
+// a conflict marker. If an installed file has been changed again since the conflict, the changes are merged
+// first and the file is reported with the new conflicts, if any.
+func Resolve(provider Provider) ([]ResolvedFile, error) {
+	d, err := LoadDeployment(provider, ScopeGlobal)
+	if err != nil || d == nil {
+		return nil, err
+	}
+
+	// Check every file before changing anything
+	var targets []string
+	for _, f := range d.Files {
+		if !f.Conflict {
+			continue
+		}
+		data, err := os.ReadFile(f.Source)
+		if err != nil {
+			return nil, err
+		}
+		if line := merge.FindConflictMarker(string(data)); line > 0 {
+			return nil, fmt.Errorf("%s:%d: %w", f.Source, line, ErrConflictMarker)
+		}
+		targets = append(targets, f.Target)
+	}
+
+	var files []ResolvedFile
+	for _, target := range targets {
+		f := d.file(target)
+		digest, err := fileio.HashFile(f.Target)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return files, errors.Join(err, d.save())
+		}
+
+		var conflicts int
+		if err == nil && digest != f.Digest {
+			conflicts, err = f.merge(d.Strategy)
+		} else {
+			f.Conflict = false
+			err = f.reinstall(d.Strategy)
+		}
+		if err != nil {
+			return files, errors.Join(fmt.Errorf("resolve %s: %w", f.Target, err), d.save())
+		}
+		files = append(files, ResolvedFile{Source: f.Source, Target: f.Target, Conflicts: conflicts})
+	}
+
+	return files, d.save()
+}
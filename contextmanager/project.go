@@ -0,0 +1,257 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zchee/llmctxenv/gitrepo"
+)
+
+// DefaultProjectMarkers are the names of the files or directories which mark the root directory of a project.
+//
+// More markers can be configured with the "project_markers" key of the [ConfigFile].
+var DefaultProjectMarkers = []string{".git", "go.mod", "package.json"}
+
+// ProjectRoot returns the nearest directory of the absolute path dir and its parents which contains one of
+// the markers, or dir itself if there is none.
+//
+// The search stops below the user home directory, so a marker in the home directory, e.g. the ".git" of
+// a dotfiles repository, does not make the home directory the root of every project in it.
+func ProjectRoot(dir string, markers []string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get current user home directory: %w", err)
+	}
+
+	for d := filepath.Clean(dir); ; {
+		if d == home && d != filepath.Clean(dir) {
+			break
+		}
+		for _, marker := range markers {
+			_, err := os.Lstat(filepath.Join(d, marker))
+			if err == nil {
+				return d, nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrPermission) {
+				return "", err
+			}
+		}
+
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+
+	return filepath.Clean(dir), nil
+}
+
+// remoteKey returns the key of the project at root identified by [IdentityRemote]: the normalized URL of
+// the "origin" remote of the git repository containing root, followed by the path of root in the working
+// tree unless it is the top-level directory. It returns false if root is not in a repository with the
+// remote.
+func remoteKey(root string) (string, bool, error) {
+	repo, err := gitrepo.Find(root)
+	if err != nil {
+		if errors.Is(err, gitrepo.ErrNotRepository) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("find git repository of %s: %w", root, err)
+	}
+	url, ok, err := repo.RemoteURL("origin")
+	if err != nil || !ok {
+		return "", false, err
+	}
+
+	key, err := gitrepo.NormalizeURL(url)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", repo.WorkTree, err)
+	}
+	rel, err := filepath.Rel(repo.WorkTree, root)
+	if err != nil {
+		return "", false, err
+	}
+	if rel != "." {
+		key += "/" + filepath.ToSlash(rel)
+	}
+	return key, true, nil
+}
+
+// Project represents a project which has a local system context of one or more providers.
+type Project struct {
+	// Path is the absolute path of the project directory. It is empty for a project identified by Remote.
+	Path string `json:"path,omitempty"`
+
+	// Remote is the key of a project identified by [IdentityRemote], see [EncodeRemoteKey].
+	Remote string `json:"remote,omitempty"`
+
+	// Exists reports whether the project directory still exists. A project identified by Remote is not
+	// bound to a directory and always exists.
+	Exists bool `json:"exists"`
+
+	// Modified is the latest modification time of the local system contexts of the project.
+	Modified time.Time `json:"modified"`
+
+	Providers []*ProjectProvider `json:"providers"`
+}
+
+// ProjectProvider represents the local system context of a [Project] for a [Provider].
+type ProjectProvider struct {
+	Provider Provider `json:"provider"`
+
+	// Dir is the directory which holds the local system context.
+	Dir string `json:"dir"`
+
+	// Environment is the name of the environment selected in the project directory by the version files and
+	// the local system context, ignoring the environment variables. It is empty if the project directory no
+	// longer exists or the project is identified by remote.
+	Environment string `json:"environment,omitempty"`
+
+	// Modified is the latest modification time of Dir and the files in it.
+	Modified time.Time `json:"modified"`
+}
+
+// Projects returns the projects which have a local system context in [LocalEnvironmentsDir] of any
+// provider, sorted by path, followed by the projects identified by remote sorted by key.
+//
+// It returns [ErrLegacyLayout] if the directories have not been migrated by [MigrateLocalDirs].
+func Projects() ([]*Project, error) {
+	projects, err := localProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range projects {
+		if p.Path == "" || !p.Exists {
+			continue
+		}
+		for _, pp := range p.Providers {
+			// The environment of the project itself, not of the current shell session
+			sel, err := SelectEnvironment(pp.Provider, p.Path, WithoutEnvVars())
+			if err != nil {
+				return nil, fmt.Errorf("resolve %s environment of %s: %w", pp.Provider, p.Path, err)
+			}
+			pp.Environment = sel.Name
+		}
+	}
+	return projects, nil
+}
+
+// localProjects returns the projects which have a local system context like [Projects], without selecting
+// their environments, so that a project whose version files are broken is still listed.
+func localProjects() ([]*Project, error) {
+	if err := checkLocalLayout(); err != nil {
+		return nil, err
+	}
+
+	projects := make(map[string]*Project)
+	for _, provider := range Providers() {
+		dir := LocalEnvironmentsDir(provider)
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("ReadDir %s: %w", dir, err)
+		}
+
+		for _, ent := range ents {
+			if !ent.IsDir() {
+				continue
+			}
+			var path, remote string
+			if strings.HasPrefix(ent.Name(), "@") {
+				remote, err = DecodeRemoteKey(ent.Name())
+			} else {
+				path, err = DecodeProjectPath(ent.Name())
+			}
+			if err != nil {
+				// Not a directory of the local layout
+				continue
+			}
+
+			p, ok := projects[ent.Name()]
+			if !ok {
+				p = &Project{
+					Path:   path,
+					Remote: remote,
+					Exists: true,
+				}
+				if path != "" {
+					fi, err := os.Stat(path)
+					p.Exists = err == nil && fi.IsDir()
+				}
+				projects[ent.Name()] = p
+			}
+
+			pp := &ProjectProvider{
+				Provider: provider,
+				Dir:      filepath.Join(dir, ent.Name()),
+			}
+			if pp.Modified, err = latestModTime(pp.Dir); err != nil {
+				return nil, err
+			}
+			if pp.Modified.After(p.Modified) {
+				p.Modified = pp.Modified
+			}
+			p.Providers = append(p.Providers, pp)
+		}
+	}
+
+	return slices.SortedFunc(maps.Values(projects), func(a, b *Project) int {
+		switch {
+		case a.Remote == "" && b.Remote != "":
+			return -1
+		case a.Remote != "" && b.Remote == "":
+			return 1
+		}
+		return cmp.Or(strings.Compare(a.Path, b.Path), strings.Compare(a.Remote, b.Remote))
+	}), nil
+}
+
+// latestModTime returns the latest modification time of dir and the files in it.
+func latestModTime(dir string) (time.Time, error) {
+	var latest time.Time
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("walk %s: %w", dir, err)
+	}
+	return latest, nil
+}
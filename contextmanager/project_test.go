@@ -0,0 +1,351 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestProjectRoot(t *testing.T) {
+	tests := map[string]struct {
+		markers []string // paths relative to the temporary directory
+		dir     string
+		want    string
+	}{
+		"git repository": {
+			markers: []string{"repo/.git/"},
+			dir:     "repo/sub/dir",
+			want:    "repo",
+		},
+		"go module": {
+			markers: []string{"repo/.git/", "repo/mod/go.mod"},
+			dir:     "repo/mod/pkg",
+			want:    "repo/mod",
+		},
+		"package json": {
+			markers: []string{"web/package.json"},
+			dir:     "web/src",
+			want:    "web",
+		},
+		"marker in dir": {
+			markers: []string{"repo/.git/"},
+			dir:     "repo",
+			want:    "repo",
+		},
+		"no marker": {
+			dir:  "plain/sub",
+			want: "plain/sub",
+		},
+		"marker in home": {
+			markers: []string{".git/"},
+			dir:     "dotfiles/sub",
+			want:    "dotfiles/sub",
+		},
+		"home itself": {
+			markers: []string{".git/"},
+			dir:     ".",
+			want:    ".",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			home := setupTestHome(t)
+			for _, m := range tt.markers {
+				path := filepath.Join(home, m)
+				if strings.HasSuffix(m, "/") {
+					if err := os.MkdirAll(path, 0o755); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dir := filepath.Join(home, tt.dir)
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := contextmanager.ProjectRoot(dir, contextmanager.DefaultProjectMarkers)
+			if err != nil {
+				t.Fatalf("ProjectRoot() error = %v", err)
+			}
+			if want := filepath.Join(home, tt.want); got != want {
+				t.Errorf("ProjectRoot() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestLocalDir_ProjectRoot(t *testing.T) {
+	root := setupTestRoot(t)
+	home := setupTestHome(t)
+
+	project := filepath.Join(home, "src", "app")
+	sub := filepath.Join(project, "internal", "pkg")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, ".hg"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := contextmanager.ProviderClaudeCode
+	got, err := contextmanager.LocalDir(provider, sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "local", provider.String(), "src-app-internal-pkg"); got != want {
+		t.Errorf("LocalDir() without marker = %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(contextmanager.ConfigFile(), []byte(`{"project_markers": [".hg"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want, err := contextmanager.LocalDir(provider, project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := contextmanager.LocalDir(provider, sub); err != nil || got != want {
+		t.Errorf("LocalDir(%q) = %q, %v, want %q", sub, got, err, want)
+	}
+
+	t.Chdir(sub)
+	if got, err := contextmanager.LocalDir(provider, ""); err != nil || got != want {
+		t.Errorf("LocalDir(\"\") = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+	recordLocalLayout(t)
+	// The variables of the current session do not select the environments of the projects
+	t.Setenv(contextmanager.EnvironmentVariable(contextmanager.ProviderCodex), contextmanager.DefaultEnvironment)
+	t.Setenv(contextmanager.EnvEnvironment, "review")
+
+	app := filepath.Join(home, "src", "app")
+	if err := os.MkdirAll(app, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	createEnvironment(t, contextmanager.ProviderCodex, "review", nil)
+	writeVersionFile(t, app, "codex=review\n")
+	gone := filepath.Join(home, "src", "gone")
+
+	for _, tt := range []struct {
+		provider contextmanager.Provider
+		project  string
+	}{
+		{contextmanager.ProviderClaudeCode, app},
+		{contextmanager.ProviderCodex, app},
+		{contextmanager.ProviderClaudeCode, gone},
+	} {
+		dir, err := contextmanager.LocalDir(tt.provider, tt.project)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	projects, err := contextmanager.Projects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("Projects() = %d projects, want 2", len(projects))
+	}
+
+	if p := projects[0]; p.Path != app || !p.Exists || len(p.Providers) != 2 || p.Modified.IsZero() {
+		t.Errorf("Projects()[0] = %+v", p)
+	} else {
+		for _, pp := range p.Providers {
+			want := contextmanager.DefaultEnvironment
+			if pp.Provider == contextmanager.ProviderCodex {
+				want = "review"
+			}
+			if pp.Environment != want {
+				t.Errorf("%s environment = %q, want %q", pp.Provider, pp.Environment, want)
+			}
+		}
+	}
+	if p := projects[1]; p.Path != gone || p.Exists || len(p.Providers) != 1 || p.Providers[0].Environment != "" {
+		t.Errorf("Projects()[1] = %+v", p)
+	}
+}
+
+func TestLocalDir_RemoteIdentity(t *testing.T) {
+	root := setupTestRoot(t)
+	home := setupTestHome(t)
+	recordLocalLayout(t)
+
+	const config = "[remote \"origin\"]\n\turl = %s\n"
+	clone := filepath.Join(home, "src", "llmctxenv")
+	other := filepath.Join(home, "work", "llmctxenv-fork")
+	worktree := filepath.Join(home, "work", "llmctxenv-feature")
+	plain := filepath.Join(home, "plain")
+	for path, content := range map[string]string{
+		filepath.Join(clone, ".git", "config"):                            fmt.Sprintf(config, "git@github.com:zchee/llmctxenv.git"),
+		filepath.Join(clone, ".git", "worktrees", "feature", "commondir"): "../..\n",
+		filepath.Join(clone, "tools", "go.mod"):                           "module tools\n",
+		filepath.Join(other, ".git", "config"):                            fmt.Sprintf(config, "https://github.com/zchee/llmctxenv"),
+		filepath.Join(worktree, ".git"):                                   "gitdir: " + filepath.Join(clone, ".git", "worktrees", "feature") + "\n",
+		filepath.Join(plain, ".git", "HEAD"):                              "ref: refs/heads/main\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(contextmanager.ConfigFile(), []byte(`{"project_identity": "remote"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	localDir := filepath.Join(root, "local", contextmanager.ProviderClaudeCode.String())
+	tests := map[string]struct {
+		dir  string
+		want string
+	}{
+		"clone":            {dir: filepath.Join(clone, "cmd"), want: "@github.com-zchee-llmctxenv"},
+		"another clone":    {dir: other, want: "@github.com-zchee-llmctxenv"},
+		"linked worktree":  {dir: worktree, want: "@github.com-zchee-llmctxenv"},
+		"nested project":   {dir: filepath.Join(clone, "tools"), want: "@github.com-zchee-llmctxenv-tools"},
+		"no origin remote": {dir: plain, want: "plain"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := os.MkdirAll(tt.dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			got, err := contextmanager.LocalDir(contextmanager.ProviderClaudeCode, tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(localDir, tt.want); got != want {
+				t.Errorf("LocalDir(%q) = %q, want %q", tt.dir, got, want)
+			}
+		})
+	}
+
+	if err := os.MkdirAll(filepath.Join(localDir, "@github.com-zchee-llmctxenv"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	projects, err := contextmanager.Projects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 || projects[0].Remote != "github.com/zchee/llmctxenv" || projects[0].Path != "" || !projects[0].Exists {
+		t.Errorf("Projects() = %+v", projects)
+	}
+}
+
+func TestActivate_RemoteIdentity(t *testing.T) {
+	root := setupTestRoot(t)
+	home := setupTestHome(t)
+	recordLocalLayout(t)
+
+	const config = "[remote \"origin\"]\n\turl = %s\n"
+	clone := filepath.Join(home, "src", "llmctxenv")
+	other := filepath.Join(home, "work", "llmctxenv-fork")
+	worktree := filepath.Join(home, "work", "llmctxenv-feature")
+	plain := filepath.Join(home, "plain")
+	for path, content := range map[string]string{
+		filepath.Join(clone, ".git", "config"):                            fmt.Sprintf(config, "git@github.com:zchee/llmctxenv.git"),
+		filepath.Join(clone, ".git", "worktrees", "feature", "commondir"): "../..\n",
+		filepath.Join(clone, "cmd", "main.go"):                            "package main\n",
+		filepath.Join(other, ".git", "config"):                            fmt.Sprintf(config, "https://github.com/zchee/llmctxenv"),
+		filepath.Join(worktree, ".git"):                                   "gitdir: " + filepath.Join(clone, ".git", "worktrees", "feature") + "\n",
+		filepath.Join(plain, ".git", "HEAD"):                              "ref: refs/heads/main\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(contextmanager.ConfigFile(), []byte(`{"project_identity": "remote"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The local context shared by the clones of the repository
+	localDir := filepath.Join(root, "local", contextmanager.ProviderClaudeCode.String(), "@github.com-zchee-llmctxenv")
+	if err := os.MkdirAll(localDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(localDir, "CLAUDE.md"), []byte("# llmctxenv\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	targetDir, err := contextmanager.TargetDir(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		dir     string
+		want    string
+		wantDir string
+	}{
+		"clone":           {dir: filepath.Join(clone, "cmd"), want: contextmanager.ProjectEnvironment, wantDir: localDir},
+		"another clone":   {dir: other, want: contextmanager.ProjectEnvironment, wantDir: localDir},
+		"linked worktree": {dir: worktree, want: contextmanager.ProjectEnvironment, wantDir: localDir},
+		"another project": {dir: plain, want: contextmanager.DefaultEnvironment, wantDir: contextmanager.GlobalDir(contextmanager.ProviderClaudeCode)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Chdir(tt.dir)
+
+			env, err := contextmanager.ResolveEnvironment(contextmanager.ProviderClaudeCode)
+			if err != nil {
+				t.Fatalf("ResolveEnvironment() unexpected error: %v", err)
+			}
+			if env.Name != tt.want || env.Dir != tt.wantDir {
+				t.Fatalf("ResolveEnvironment() = %+v, want %s in %s", env, tt.want, tt.wantDir)
+			}
+			if tt.want != contextmanager.ProjectEnvironment {
+				return
+			}
+
+			if _, err := contextmanager.Activate(env, contextmanager.StrategyCopy); err != nil {
+				t.Fatalf("Activate() unexpected error: %v", err)
+			}
+			if got, want := readFile(t, filepath.Join(targetDir, "CLAUDE.md")), "# llmctxenv\n"; got != want {
+				t.Errorf("installed CLAUDE.md = %q, want %q", got, want)
+			}
+			s, err := contextmanager.StatusOf(contextmanager.ProviderClaudeCode)
+			if err != nil {
+				t.Fatalf("StatusOf() unexpected error: %v", err)
+			}
+			if s.Drifted() {
+				t.Errorf("StatusOf() = %+v, want not drifted", s)
+			}
+		})
+	}
+}
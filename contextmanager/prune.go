@@ -0,0 +1,88 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TrashDir returns the directory which holds the local system contexts removed by [Prune].
+//
+// Each prune moves the directories into a subdirectory named by the time of the prune, which can be
+// moved back to [LocalEnvironmentsDir] to recover them.
+func TrashDir() string {
+	return filepath.Join(LLMCtxEnvRoot, "trash")
+}
+
+// PrunedDir represents a local system context directory removed by [Prune].
+type PrunedDir struct {
+	Provider Provider
+
+	// Project is the path of the project which no longer exists.
+	Project string
+
+	// Dir is the local system context directory.
+	Dir string
+
+	// Trash is the path Dir has been moved to.
+	Trash string
+}
+
+// Prune removes the local system contexts of a given provider whose project directory no longer exists by
+// moving them to the [TrashDir]. If dryRun is true, it only reports what would be removed.
+//
+// The environments of the projects are not selected, so the version files of the existing projects do not
+// affect it. It returns [ErrLegacyLayout] if the directories have not been migrated by [MigrateLocalDirs].
+func Prune(provider Provider, dryRun bool) ([]PrunedDir, error) {
+	projects, err := localProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	trash := filepath.Join(TrashDir(), time.Now().UTC().Format("20060102T150405Z"))
+	var pruned []PrunedDir
+	for _, p := range projects {
+		if p.Exists {
+			continue
+		}
+		for _, pp := range p.Providers {
+			if pp.Provider != provider {
+				continue
+			}
+			d := PrunedDir{
+				Provider: provider,
+				Project:  p.Path,
+				Dir:      pp.Dir,
+				Trash:    filepath.Join(trash, provider.String(), filepath.Base(pp.Dir)),
+			}
+			if !dryRun {
+				if err := os.MkdirAll(filepath.Dir(d.Trash), 0o700); err != nil {
+					return pruned, fmt.Errorf("mkdir all %s path: %w", filepath.Dir(d.Trash), err)
+				}
+				if err := os.Rename(d.Dir, d.Trash); err != nil {
+					return pruned, fmt.Errorf("move %s to trash: %w", d.Dir, err)
+				}
+			}
+			pruned = append(pruned, d)
+		}
+	}
+
+	return pruned, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestPrune(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+	recordLocalLayout(t)
+
+	app := filepath.Join(home, "app")
+	if err := os.MkdirAll(app, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	provider := contextmanager.ProviderClaudeCode
+	dirs := make(map[string]string)
+	for _, project := range []string{app, filepath.Join(home, "gone")} {
+		dir, err := contextmanager.LocalDir(provider, project)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "CLAUDE.md"), []byte(project), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		dirs[project] = dir
+	}
+	gone := filepath.Join(home, "gone")
+	// A broken version file of an existing project does not prevent the prune
+	writeVersionFile(t, app, "broken\n")
+
+	for _, dryRun := range []bool{true, false} {
+		pruned, err := contextmanager.Prune(provider, dryRun)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pruned) != 1 || pruned[0].Project != gone || pruned[0].Dir != dirs[gone] {
+			t.Fatalf("Prune(dryRun=%t) = %+v", dryRun, pruned)
+		}
+		if _, err := os.Stat(dirs[gone]); (err == nil) != dryRun {
+			t.Errorf("Prune(dryRun=%t) left %s: %v", dryRun, dirs[gone], err)
+		}
+		if !dryRun {
+			if got := readFile(t, filepath.Join(pruned[0].Trash, "CLAUDE.md")); got != gone {
+				t.Errorf("trashed content = %q, want %q", got, gone)
+			}
+		}
+	}
+	if _, err := os.Stat(dirs[app]); err != nil {
+		t.Errorf("Prune() removed the existing project: %v", err)
+	}
+
+	if pruned, err := contextmanager.Prune(contextmanager.ProviderCodex, false); err != nil || len(pruned) != 0 {
+		t.Errorf("Prune(codex) = %+v, %v, want nothing", pruned, err)
+	}
+}
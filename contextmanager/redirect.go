@@ -0,0 +1,142 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrRedirectUnsupported is returned when the CLI of a provider cannot be redirected to another home.
+var ErrRedirectUnsupported = errors.New("provider does not support redirection")
+
+// HomeVariable returns the environment variable which redirects the CLI of a given provider to another
+// home directory.
+//
+// It returns an error wrapping [ErrRedirectUnsupported] if the CLI has no such variable.
+func HomeVariable(provider Provider) (string, error) {
+	loc, ok := Locations[provider]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownProvider, provider)
+	}
+	if loc.HomeEnv == "" {
+		return "", fmt.Errorf("%s: %w", provider, ErrRedirectUnsupported)
+	}
+	return loc.HomeEnv, nil
+}
+
+// HomesDir returns the directory path that holds the homes materialized for the environments of a given
+// provider.
+func HomesDir(provider Provider) string {
+	return filepath.Join(LLMCtxEnvRoot, "homes", provider.String())
+}
+
+// HomeDir returns the directory path of the home materialized for the named environment of a given provider.
+func HomeDir(provider Provider, name string) string {
+	return filepath.Join(HomesDir(provider), name)
+}
+
+// MaterializeHome builds the provider home of the [Environment] in [HomeDir], and returns its path.
+//
+// The home holds symbolic links to the context files of the environment, and to every other entry of the
+// [TargetDir] of the provider, so that the CLI redirected to it with the [HomeVariable] shares the settings and
+// credentials of the user while it reads the context of the environment. The files in the real home are never
+// changed. Building the home again updates the links, and the entries added to the home by the CLI are kept.
+func MaterializeHome(env *Environment) (string, error) {
+	if _, err := HomeVariable(env.Provider); err != nil {
+		return "", err
+	}
+	targetDir, err := TargetDir(env.Provider)
+	if err != nil {
+		return "", err
+	}
+	files, err := env.Files()
+	if err != nil {
+		return "", fmt.Errorf("list %s environment %s files: %w", env.Provider, env.Name, err)
+	}
+	sourceDir, err := env.SourceDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := HomeDir(env.Provider, env.Name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("mkdir all %s path: %w", dir, err)
+	}
+
+	links := make(map[string]string)
+	ents, err := os.ReadDir(targetDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("ReadDir %s: %w", targetDir, err)
+	}
+	for _, ent := range ents {
+		links[ent.Name()] = filepath.Join(targetDir, ent.Name())
+	}
+	for _, name := range ContextFiles[env.Provider] {
+		delete(links, name)
+		if slices.Contains(files, name) {
+			links[name] = filepath.Join(sourceDir, name)
+		}
+	}
+
+	// Remove the links which are no longer wanted, the other files have been made by the CLI
+	ents, err = os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("ReadDir %s: %w", dir, err)
+	}
+	for _, ent := range ents {
+		path := filepath.Join(dir, ent.Name())
+		link, err := os.Readlink(path)
+		if err != nil {
+			continue
+		}
+		if want, ok := links[ent.Name()]; ok && want == link {
+			delete(links, ent.Name())
+			continue
+		}
+		if !isWithin(link, targetDir) && !isWithin(link, LLMCtxEnvRoot) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return "", fmt.Errorf("remove %s: %w", path, err)
+		}
+	}
+
+	for name, link := range links {
+		path := filepath.Join(dir, name)
+		if err := os.Symlink(link, path); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				// The CLI has made its own file
+				continue
+			}
+			return "", fmt.Errorf("link %s: %w", path, err)
+		}
+	}
+
+	return dir, nil
+}
+
+// isWithin reports whether path is dir or a path under dir.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
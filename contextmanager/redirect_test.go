@@ -0,0 +1,118 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestHomeVariable(t *testing.T) {
+	tests := map[string]struct {
+		provider contextmanager.Provider
+		want     string
+		wantErr  error
+	}{
+		"claude": {
+			provider: contextmanager.ProviderClaudeCode,
+			want:     "CLAUDE_CONFIG_DIR",
+		},
+		"codex": {
+			provider: contextmanager.ProviderCodex,
+			want:     "CODEX_HOME",
+		},
+		"goose": {
+			provider: contextmanager.ProviderGoose,
+			wantErr:  contextmanager.ErrRedirectUnsupported,
+		},
+		"unknown": {
+			provider: "unknown",
+			wantErr:  contextmanager.ErrUnknownProvider,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := contextmanager.HomeVariable(tt.provider)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("HomeVariable(%s) = %q, %v, want %q, %v", tt.provider, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaterializeHome(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	provider := contextmanager.ProviderClaudeCode
+	realDir := filepath.Join(home, ".claude")
+	if err := os.MkdirAll(filepath.Join(realDir, "projects"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"CLAUDE.md": "real", "settings.json": "{}"} {
+		if err := os.WriteFile(filepath.Join(realDir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	review := createEnvironment(t, provider, "review", map[string]string{"CLAUDE.md": "review"})
+
+	dir, err := contextmanager.MaterializeHome(review)
+	if err != nil {
+		t.Fatalf("MaterializeHome() unexpected error: %v", err)
+	}
+	if want := contextmanager.HomeDir(provider, "review"); dir != want {
+		t.Errorf("MaterializeHome() = %q, want %q", dir, want)
+	}
+	if got := readFile(t, filepath.Join(dir, "CLAUDE.md")); got != "review" {
+		t.Errorf("context file = %q, want %q", got, "review")
+	}
+	if got := readFile(t, filepath.Join(dir, "settings.json")); got != "{}" {
+		t.Errorf("settings.json = %q, want the real one", got)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "projects")); err != nil || !fi.IsDir() {
+		t.Errorf("projects should be linked to the real directory, got err = %v", err)
+	}
+	if got := readFile(t, filepath.Join(realDir, "CLAUDE.md")); got != "real" {
+		t.Errorf("real context file = %q, must not be changed", got)
+	}
+
+	// Entries made by the CLI are kept, the links follow the environment and the real home
+	if err := os.WriteFile(filepath.Join(dir, "history.jsonl"), []byte("made by the CLI"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(review.Dir, "CLAUDE.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(realDir, "settings.json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := contextmanager.MaterializeHome(review); err != nil {
+		t.Fatalf("MaterializeHome() again unexpected error: %v", err)
+	}
+	for _, name := range []string{"CLAUDE.md", "settings.json"} {
+		if _, err := os.Lstat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s should be removed from the home, got err = %v", name, err)
+		}
+	}
+	if got := readFile(t, filepath.Join(dir, "history.jsonl")); got != "made by the CLI" {
+		t.Errorf("history.jsonl = %q, want kept", got)
+	}
+}
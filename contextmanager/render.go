@@ -0,0 +1,281 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/zchee/llmctxenv/fileio"
+	"github.com/zchee/llmctxenv/preprocess"
+)
+
+// CanonicalFile is the name of the context file in [SharedDir] which is rendered into the context files of
+// every provider.
+const CanonicalFile = "CONTEXT.md"
+
+// SharedEnvironmentsDir returns the directory path that holds the environments shared by the providers.
+func SharedEnvironmentsDir() string {
+	return filepath.Join(LLMCtxEnvRoot, "shared")
+}
+
+// SharedDir returns the directory path of the named environment shared by the providers, which holds the
+// [CanonicalFile].
+func SharedDir(name string) string {
+	return filepath.Join(SharedEnvironmentsDir(), name)
+}
+
+// renderedRoot returns the directory path that holds the rendered environments of every provider.
+func renderedRoot() string {
+	return filepath.Join(LLMCtxEnvRoot, "rendered")
+}
+
+// RenderedEnvironmentsDir returns the directory path that holds the rendered environments of a given provider.
+func RenderedEnvironmentsDir(provider Provider) string {
+	return filepath.Join(renderedRoot(), provider.String())
+}
+
+// RenderedDir returns the directory path that holds the context files of the named environment of a given
+// provider rendered from the [CanonicalFile].
+func RenderedDir(provider Provider, name string) string {
+	return filepath.Join(RenderedEnvironmentsDir(provider), name)
+}
+
+// CreateSharedEnvironment creates the named environment shared by the providers.
+//
+// It returns an error wrapping [fs.ErrExist] if the environment already exists.
+func CreateSharedEnvironment(name string) (string, error) {
+	if err := ValidateEnvironmentName(name); err != nil {
+		return "", err
+	}
+	if isImplicit(name) {
+		return "", fmt.Errorf("shared environment %s: %w", name, fs.ErrExist)
+	}
+
+	dir := SharedDir(name)
+	if err := os.MkdirAll(SharedEnvironmentsDir(), 0o700); err != nil {
+		return "", fmt.Errorf("mkdir all %s path: %w", SharedEnvironmentsDir(), err)
+	}
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		return "", fmt.Errorf("shared environment %s: %w", name, err)
+	}
+	return dir, nil
+}
+
+// isShared reports whether the named environment shared by the providers exists.
+func isShared(name string) bool {
+	fi, err := os.Stat(SharedDir(name))
+	return err == nil && fi.IsDir()
+}
+
+// canonical returns the contents of the [CanonicalFile] of the [Environment], or nil if it has none.
+func (e *Environment) canonical() ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(SharedDir(e.Name), CanonicalFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// IsRendered reports whether the context files of the [Environment] are rendered from the [CanonicalFile].
+func (e *Environment) IsRendered() bool {
+	fi, err := os.Stat(filepath.Join(SharedDir(e.Name), CanonicalFile))
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// SourceDir returns the directory path which holds the context files of the [Environment] to be installed.
+//
+// It is Dir, unless the environment has the [CanonicalFile]: then each context file of the provider is
+// rendered into [RenderedDir] from the canonical file, followed by the file of the same name in Dir, if any,
+// which overrides it. The conditional sections of both files are processed for the provider with
+// [preprocess.Process]. The rendered files are rewritten only when their content changes.
+//
+// It returns an error wrapping [ErrModifiedFile] without rewriting anything if a rendered file to be
+// rewritten has been changed since it was installed, e.g. edited through an installed symbolic link, as the
+// changes would be lost. It returns a [*preprocess.Error] if a context file of an environment which is not
+// rendered contains a conditional section marker, which would be installed verbatim.
+func (e *Environment) SourceDir() (string, error) {
+	rendered, err := e.renderFiles()
+	if err != nil {
+		return "", err
+	}
+	if rendered == nil {
+		if err := e.checkMarkers(); err != nil {
+			return "", err
+		}
+		return e.Dir, nil
+	}
+
+	dir := RenderedDir(e.Provider, e.Name)
+	d, err := LoadDeployment(e.Provider, ScopeGlobal)
+	if err != nil {
+		return "", err
+	}
+	for name, data := range rendered {
+		if err := e.checkRendered(d, filepath.Join(dir, name), data); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("mkdir all %s path: %w", dir, err)
+	}
+	for name, data := range rendered {
+		if err := writeRendered(filepath.Join(dir, name), data); err != nil {
+			return "", fmt.Errorf("render %s: %w", filepath.Join(dir, name), err)
+		}
+	}
+
+	return dir, nil
+}
+
+// renderFiles returns the contents of the context files of the [Environment] rendered as described in
+// [Environment.SourceDir] keyed by the file name, without writing them. It returns nil if the environment
+// has no [CanonicalFile].
+func (e *Environment) renderFiles() (map[string][]byte, error) {
+	canonical := filepath.Join(SharedDir(e.Name), CanonicalFile)
+	data, err := e.canonical()
+	if err != nil {
+		return nil, fmt.Errorf("read %s environment %s %s: %w", e.Provider, e.Name, CanonicalFile, err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	target := preprocess.Target{Provider: e.Provider.String()}
+	for _, p := range Providers() {
+		target.Providers = append(target.Providers, p.String())
+	}
+	if data, err = preprocess.Process(canonical, data, target); err != nil {
+		return nil, err
+	}
+
+	rendered := make(map[string][]byte)
+	for _, name := range ContextFiles[e.Provider] {
+		path := filepath.Join(e.Dir, name)
+		override, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if override, err = preprocess.Process(path, override, target); err != nil {
+			return nil, err
+		}
+		rendered[name] = render(data, override)
+	}
+	return rendered, nil
+}
+
+// checkMarkers returns a [*preprocess.Error] locating the first conditional section marker in the context
+// files of the [Environment] in Dir, which are installed as they are.
+func (e *Environment) checkMarkers() error {
+	for _, name := range ContextFiles[e.Provider] {
+		path := filepath.Join(e.Dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		if line, ok := preprocess.FirstMarker(data); ok {
+			return &preprocess.Error{
+				File: path,
+				Line: line,
+				Msg:  "conditional sections are processed only in shared environments with " + CanonicalFile,
+			}
+		}
+	}
+	return nil
+}
+
+// checkRendered returns an error wrapping [ErrModifiedFile] if the rendered file at path, which is to be
+// replaced by data, has been changed since it was installed by the [Deployment] d.
+func (e *Environment) checkRendered(d *Deployment, path string, data []byte) error {
+	old, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if bytes.Equal(old, data) || d == nil {
+		return nil
+	}
+
+	for _, f := range d.Files {
+		if f.Source != path || f.Digest == "" {
+			continue
+		}
+		digest, err := fileio.HashFile(path)
+		if err != nil {
+			return err
+		}
+		if digest != f.Digest {
+			canonical := filepath.Join(SharedDir(e.Name), CanonicalFile)
+			return fmt.Errorf("%s: %w, move the changes into %s or the overrides in %s and remove it", path, ErrModifiedFile, canonical, e.Dir)
+		}
+	}
+	return nil
+}
+
+// render returns the context file rendered from the canonical file contents and the override.
+func render(canonical, override []byte) []byte {
+	if len(override) == 0 {
+		return canonical
+	}
+
+	out := bytes.Clone(canonical)
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	if len(out) > 0 {
+		out = append(out, '\n')
+	}
+	return append(out, override...)
+}
+
+// writeRendered writes data to the rendered file at path unless it already holds data, so that the
+// installed hard links and the recorded digests stay valid.
+func writeRendered(path string, data []byte) error {
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".rendered-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
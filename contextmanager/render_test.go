@@ -0,0 +1,261 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+	"github.com/zchee/llmctxenv/preprocess"
+)
+
+// createSharedEnvironment creates the named shared environment with the given canonical file contents.
+func createSharedEnvironment(t *testing.T, name, content string) {
+	t.Helper()
+
+	dir, err := contextmanager.CreateSharedEnvironment(name)
+	if err != nil {
+		t.Fatalf("CreateSharedEnvironment(%q) unexpected error: %v", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, contextmanager.CanonicalFile), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnvironment_SourceDir(t *testing.T) {
+	tests := map[string]struct {
+		canonical string // contents of the canonical file, none if empty
+		files     map[string]string
+		want      map[string]string
+	}{
+		"Canonical": {
+			canonical: "# shared\n",
+			want:      map[string]string{"CLAUDE.md": "# shared\n"},
+		},
+		"Override": {
+			canonical: "# shared",
+			files:     map[string]string{"CLAUDE.md": "# claude\n"},
+			want:      map[string]string{"CLAUDE.md": "# shared\n\n# claude\n"},
+		},
+		"Conditional": {
+			canonical: "# shared\n<!-- llmctxenv:if not provider=claude -->\nnot for claude\n<!-- llmctxenv:end -->\n",
+			files:     map[string]string{"CLAUDE.md": "<!-- llmctxenv:if provider=claude -->\n# claude\n<!-- llmctxenv:end -->\n"},
+			want:      map[string]string{"CLAUDE.md": "# shared\n\n# claude\n"},
+		},
+		"NoCanonical": {
+			files: map[string]string{"CLAUDE.md": "# claude\n"},
+			want:  map[string]string{"CLAUDE.md": "# claude\n"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			setupTestRoot(t)
+
+			provider := contextmanager.ProviderClaudeCode
+			if tt.canonical != "" {
+				createSharedEnvironment(t, "review", tt.canonical)
+			}
+			if tt.files != nil {
+				createEnvironment(t, provider, "review", tt.files)
+			}
+
+			env, err := contextmanager.LookupEnvironment(provider, "review")
+			if err != nil {
+				t.Fatalf("LookupEnvironment() unexpected error: %v", err)
+			}
+			files, err := env.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir, err := env.SourceDir()
+			if err != nil {
+				t.Fatalf("SourceDir() unexpected error: %v", err)
+			}
+			wantDir := env.Dir
+			if tt.canonical != "" {
+				wantDir = contextmanager.RenderedDir(provider, "review")
+			}
+			if dir != wantDir {
+				t.Errorf("SourceDir() = %q, want %q", dir, wantDir)
+			}
+
+			got := make(map[string]string)
+			for _, name := range files {
+				got[name] = readFile(t, filepath.Join(dir, name))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rendered files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActivate_Rendered(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	createSharedEnvironment(t, "review", "# shared\n")
+	createEnvironment(t, contextmanager.ProviderCodex, "review", map[string]string{"AGENTS.md": "# codex\n"})
+
+	for provider, want := range map[contextmanager.Provider]string{
+		contextmanager.ProviderClaudeCode: "# shared\n",
+		contextmanager.ProviderCodex:      "# shared\n\n# codex\n",
+		contextmanager.ProviderGoose:      "# shared\n",
+	} {
+		names, err := contextmanager.Environments(provider)
+		if err != nil || !reflect.DeepEqual(names, []string{contextmanager.DefaultEnvironment, "review"}) {
+			t.Errorf("Environments(%s) = %v, %v, want the shared environment", provider, names, err)
+		}
+
+		env, err := contextmanager.LookupEnvironment(provider, "review")
+		if err != nil {
+			t.Fatalf("LookupEnvironment(%s) unexpected error: %v", provider, err)
+		}
+		d, err := contextmanager.Activate(env, contextmanager.StrategySymlink)
+		if err != nil {
+			t.Fatalf("Activate(%s) unexpected error: %v", provider, err)
+		}
+		if len(d.Files) != 1 {
+			t.Fatalf("Activate(%s) = %+v, want one file installed", provider, d)
+		}
+		if got := readFile(t, d.Files[0].Target); got != want {
+			t.Errorf("%s = %q, want %q", d.Files[0].Target, got, want)
+		}
+	}
+
+	// Changes made to a rendered file are not pulled into it
+	target := filepath.Join(home, ".claude", "CLAUDE.md")
+	if err := os.Remove(target); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("# edited\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := contextmanager.Sync(contextmanager.ProviderClaudeCode, false)
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0].Action != contextmanager.SyncSkip {
+		t.Errorf("Sync() = %+v, want the rendered file skipped", files)
+	}
+}
+
+func TestEnvironment_SourceDir_Modified(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	provider := contextmanager.ProviderClaudeCode
+	createSharedEnvironment(t, "review", "# shared\n")
+	env, err := contextmanager.LookupEnvironment(provider, "review")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := contextmanager.Activate(env, contextmanager.StrategySymlink); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rendered file is edited through the installed link, then the canonical file changes
+	target := filepath.Join(home, ".claude", "CLAUDE.md")
+	if err := os.WriteFile(target, []byte("# edited\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(contextmanager.SharedDir("review"), contextmanager.CanonicalFile), []byte("# changed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.SourceDir(); !errors.Is(err, contextmanager.ErrModifiedFile) {
+		t.Errorf("SourceDir() error = %v, want %v", err, contextmanager.ErrModifiedFile)
+	}
+	if _, err := contextmanager.Activate(env, contextmanager.StrategySymlink); !errors.Is(err, contextmanager.ErrModifiedFile) {
+		t.Errorf("Activate() error = %v, want %v", err, contextmanager.ErrModifiedFile)
+	}
+	if got := readFile(t, target); got != "# edited\n" {
+		t.Errorf("%s = %q, want the edit kept", target, got)
+	}
+}
+
+func TestDiffEnvironments_Rendered(t *testing.T) {
+	setupTestRoot(t)
+	setupTestHome(t)
+
+	provider := contextmanager.ProviderClaudeCode
+	createSharedEnvironment(t, "review", "# shared\n")
+	review, err := contextmanager.LookupEnvironment(provider, "review")
+	if err != nil {
+		t.Fatal(err)
+	}
+	global := createEnvironment(t, provider, contextmanager.DefaultEnvironment, map[string]string{"CLAUDE.md": "# shared\n"})
+
+	diff, err := contextmanager.DiffEnvironments(global, review)
+	if err != nil {
+		t.Fatalf("DiffEnvironments() unexpected error: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("DiffEnvironments() = %q, want no difference", diff)
+	}
+	diff, err = contextmanager.DiffInstalled(review)
+	if err != nil {
+		t.Fatalf("DiffInstalled() unexpected error: %v", err)
+	}
+	if diff == "" {
+		t.Error("DiffInstalled() = \"\", want the rendered file missing from the provider location")
+	}
+
+	// Nothing is rendered on disk by a diff
+	if _, err := os.Stat(contextmanager.RenderedDir(provider, "review")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("rendered directory should not be written, got err = %v", err)
+	}
+}
+
+func TestEnvironment_SourceDir_Unbalanced(t *testing.T) {
+	setupTestRoot(t)
+
+	createSharedEnvironment(t, "review", "# shared\n<!-- llmctxenv:if provider=claude -->\n")
+	env, err := contextmanager.LookupEnvironment(contextmanager.ProviderClaudeCode, "review")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = env.SourceDir()
+	var perr *preprocess.Error
+	if !errors.As(err, &perr) || perr.File != filepath.Join(contextmanager.SharedDir("review"), contextmanager.CanonicalFile) || perr.Line != 2 {
+		t.Errorf("SourceDir() error = %v, want the unclosed marker at line 2 of the canonical file", err)
+	}
+}
+
+func TestActivate_MarkersNotRendered(t *testing.T) {
+	setupTestRoot(t)
+	setupTestHome(t)
+
+	provider := contextmanager.ProviderClaudeCode
+	env := createEnvironment(t, provider, "review", map[string]string{
+		"CLAUDE.md": "# claude\n<!-- llmctxenv:if provider=claude -->\n# only claude\n<!-- llmctxenv:end -->\n",
+	})
+
+	_, err := contextmanager.Activate(env, contextmanager.StrategySymlink)
+	var perr *preprocess.Error
+	if !errors.As(err, &perr) || perr.File != filepath.Join(env.Dir, "CLAUDE.md") || perr.Line != 2 {
+		t.Errorf("Activate() error = %v, want the marker at line 2 of CLAUDE.md", err)
+	}
+	if d, err := contextmanager.LoadDeployment(provider, contextmanager.ScopeGlobal); err != nil || d != nil {
+		t.Errorf("LoadDeployment() = %+v, %v, want nothing activated", d, err)
+	}
+}
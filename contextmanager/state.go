@@ -0,0 +1,119 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// State is the state journal of llmctxenv. It records the [Deployment] of each provider and scope, which
+// holds the activated environment, the installed paths, the [Strategy] and the digest of each installed file.
+type State struct {
+	Deployments []*Deployment `json:"deployments"`
+}
+
+// StateFile returns the path of the file recording the [State].
+func StateFile() string {
+	return filepath.Join(LLMCtxEnvRoot, "state.json")
+}
+
+// LoadState loads the [State] from the [StateFile].
+//
+// It returns an empty state if nothing has been recorded.
+func LoadState() (*State, error) {
+	path := StateFile()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &State{}, nil
+		}
+		return nil, err
+	}
+
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return &st, nil
+}
+
+// Lookup returns the [Deployment] of a given provider and scope, or nil if there is none.
+func (st *State) Lookup(provider Provider, scope Scope) *Deployment {
+	for _, d := range st.Deployments {
+		if d.Provider == provider && d.Scope == scope {
+			return d
+		}
+	}
+	return nil
+}
+
+// put records d replacing the deployment of the same provider and scope.
+func (st *State) put(d *Deployment) {
+	st.delete(d.Provider, d.Scope)
+	st.Deployments = append(st.Deployments, d)
+	slices.SortFunc(st.Deployments, func(a, b *Deployment) int {
+		return cmp.Or(cmp.Compare(a.Provider, b.Provider), cmp.Compare(a.Scope, b.Scope))
+	})
+}
+
+// delete removes the deployment of a given provider and scope.
+func (st *State) delete(provider Provider, scope Scope) {
+	st.Deployments = slices.DeleteFunc(st.Deployments, func(d *Deployment) bool {
+		return d.Provider == provider && d.Scope == scope
+	})
+}
+
+// save atomically writes st to the [StateFile].
+func (st *State) save() error {
+	path := StateFile()
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("mkdir all %s path: %w", dir, err)
+	}
+
+	if st.Deployments == nil {
+		st.Deployments = []*Deployment{}
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write a temporary file and rename it, so that a failure never leaves a truncated state behind
+	tmp, err := os.CreateTemp(dir, ".state-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
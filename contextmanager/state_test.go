@@ -0,0 +1,104 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+	"github.com/zchee/llmctxenv/fileio"
+)
+
+func TestLoadState(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	st, err := contextmanager.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() unexpected error: %v", err)
+	}
+	if len(st.Deployments) != 0 {
+		t.Errorf("LoadState() = %+v, want empty state", st)
+	}
+
+	before := time.Now()
+	claude := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "claude"})
+	if _, err := contextmanager.Activate(claude, contextmanager.StrategyCopy); err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	codex := createEnvironment(t, contextmanager.ProviderCodex, "review", map[string]string{"AGENTS.md": "codex"})
+	if _, err := contextmanager.Activate(codex, contextmanager.StrategySymlink); err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+
+	st, err = contextmanager.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() unexpected error: %v", err)
+	}
+	tests := map[contextmanager.Provider]struct {
+		env      *contextmanager.Environment
+		strategy contextmanager.Strategy
+		target   string
+	}{
+		contextmanager.ProviderClaudeCode: {
+			env:      claude,
+			strategy: contextmanager.StrategyCopy,
+			target:   filepath.Join(home, ".claude", "CLAUDE.md"),
+		},
+		contextmanager.ProviderCodex: {
+			env:      codex,
+			strategy: contextmanager.StrategySymlink,
+			target:   filepath.Join(home, ".codex", "AGENTS.md"),
+		},
+	}
+	if len(st.Deployments) != len(tests) {
+		t.Fatalf("LoadState() has %d deployments, want %d", len(st.Deployments), len(tests))
+	}
+	for provider, tt := range tests {
+		d := st.Lookup(provider, contextmanager.ScopeGlobal)
+		if d == nil {
+			t.Errorf("Lookup(%s) = nil, want the deployment", provider)
+			continue
+		}
+		if d.Environment != tt.env.Name || d.Strategy != tt.strategy || d.Time.Before(before.Truncate(time.Second)) {
+			t.Errorf("Lookup(%s) = %+v, want environment %s with %s strategy", provider, d, tt.env.Name, tt.strategy)
+		}
+		if len(d.Files) != 1 || d.Files[0].Target != tt.target {
+			t.Fatalf("Lookup(%s).Files = %+v, want %s", provider, d.Files, tt.target)
+		}
+		digest, err := fileio.HashFile(tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Files[0].Digest != digest {
+			t.Errorf("Lookup(%s).Files[0].Digest = %s, want %s", provider, d.Files[0].Digest, digest)
+		}
+	}
+
+	if _, _, err := contextmanager.Deactivate(contextmanager.ProviderClaudeCode, false); err != nil {
+		t.Fatalf("Deactivate() unexpected error: %v", err)
+	}
+	st, err = contextmanager.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() unexpected error: %v", err)
+	}
+	if st.Lookup(contextmanager.ProviderClaudeCode, contextmanager.ScopeGlobal) != nil || st.Lookup(contextmanager.ProviderCodex, contextmanager.ScopeGlobal) == nil {
+		t.Errorf("LoadState() after Deactivate() = %+v, want only the codex deployment", st)
+	}
+}
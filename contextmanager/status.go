@@ -0,0 +1,295 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/zchee/llmctxenv/fileio"
+)
+
+// FileStatus represents the state of an installed context file compared to its managed source.
+type FileStatus string
+
+// String returns a string representation of the [FileStatus].
+func (s FileStatus) String() string { return string(s) }
+
+const (
+	// StatusInSync means the installed file and its managed source still hold the installed content.
+	StatusInSync FileStatus = "in-sync"
+
+	// StatusModified means the installed file has been changed since the installation, typically by the
+	// provider CLI. Changes made through a symbolic or hard link also change the managed source.
+	StatusModified FileStatus = "modified"
+
+	// StatusOutdated means the managed source has been changed since the installation while the installed
+	// copy has not.
+	StatusOutdated FileStatus = "outdated"
+
+	// StatusSourceMissing means the managed source has been removed from the environment.
+	StatusSourceMissing FileStatus = "source-missing"
+
+	// StatusMissing means the installed file has been removed.
+	StatusMissing FileStatus = "missing"
+
+	// StatusReplaced means the installed file has been replaced by an unmanaged symbolic link or
+	// non-regular file.
+	StatusReplaced FileStatus = "replaced"
+
+	// StatusConflict means the changes made to the installed file conflicted with the changes made to the
+	// managed source, which holds the conflict markers until [Resolve].
+	StatusConflict FileStatus = "conflict"
+
+	// StatusNotDeployed means a context file of the active environment has not been installed.
+	StatusNotDeployed FileStatus = "not-deployed"
+)
+
+// Status returns the [FileStatus] of the installed file f.
+func (f DeployedFile) Status() (FileStatus, error) {
+	fi, err := os.Lstat(f.Target)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return StatusMissing, nil
+		}
+		return "", err
+	}
+	if f.Conflict {
+		return StatusConflict, nil
+	}
+
+	sourceDigest, err := fileio.HashFile(f.Source)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	sourceMissing := err != nil
+
+	switch {
+	case fi.Mode()&fs.ModeSymlink != 0:
+		dest, err := os.Readlink(f.Target)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case dest != f.Source:
+			return StatusReplaced, nil
+		case sourceMissing:
+			return StatusSourceMissing, nil
+		case f.Digest != "" && sourceDigest != f.Digest:
+			return StatusModified, nil
+		}
+		return StatusInSync, nil
+
+	case fi.Mode().IsRegular():
+		// A hard link shares the content with the source file
+		if sfi, err := os.Stat(f.Source); err == nil && os.SameFile(fi, sfi) {
+			if f.Digest != "" && sourceDigest != f.Digest {
+				return StatusModified, nil
+			}
+			return StatusInSync, nil
+		}
+
+		digest, err := fileio.HashFile(f.Target)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case !sourceMissing && digest == sourceDigest:
+			return StatusInSync, nil
+		case digest != f.Digest:
+			return StatusModified, nil
+		case sourceMissing:
+			return StatusSourceMissing, nil
+		}
+		return StatusOutdated, nil
+
+	default:
+		return StatusReplaced, nil
+	}
+}
+
+// removable reports whether the target of f can be removed without losing any content which is not kept
+// in its managed source.
+//
+// It returns an error wrapping [fs.ErrNotExist] if the target does not exist.
+func (f DeployedFile) removable() (bool, error) {
+	linked, err := f.linked()
+	if err != nil || linked {
+		return linked, err
+	}
+
+	fi, err := os.Lstat(f.Target)
+	if err != nil {
+		return false, err
+	}
+	if !fi.Mode().IsRegular() {
+		return false, nil
+	}
+	// The installed content at the time of the conflict is kept in BaseDir
+	if f.Conflict {
+		digest, err := fileio.HashFile(f.Target)
+		return digest == f.Digest, err
+	}
+	st, err := f.Status()
+	if err != nil {
+		return false, err
+	}
+	return st != StatusModified, nil
+}
+
+// linked reports whether the target of f is a symbolic link to its managed source or a hard link sharing
+// the content with it.
+//
+// It returns an error wrapping [fs.ErrNotExist] if the target does not exist.
+func (f DeployedFile) linked() (bool, error) {
+	fi, err := os.Lstat(f.Target)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case fi.Mode()&fs.ModeSymlink != 0:
+		dest, err := os.Readlink(f.Target)
+		if err != nil {
+			return false, err
+		}
+		return dest == f.Source, nil
+
+	case fi.Mode().IsRegular():
+		sfi, err := os.Stat(f.Source)
+		return err == nil && os.SameFile(fi, sfi), nil
+
+	default:
+		return false, nil
+	}
+}
+
+// ProviderStatus reports the deployment state of a [Provider].
+type ProviderStatus struct {
+	Provider Provider
+
+	// Environment is the name of the active environment.
+	Environment string
+
+	// Selection reports how the active environment is selected. It is nil if the selection failed.
+	Selection *Selection
+
+	// EnvironmentErr is the error resolving the active environment, if any.
+	EnvironmentErr error
+
+	// Deployment is the current deployment, or nil if the provider has not been activated.
+	Deployment *Deployment
+
+	// Files reports the state of each context file.
+	Files []FileState
+}
+
+// FileState reports the [FileStatus] of a single context file.
+type FileState struct {
+	Target string
+	Status FileStatus
+}
+
+// Switched reports whether the provider location holds an environment other than the active environment.
+func (s *ProviderStatus) Switched() bool {
+	return s.Deployment != nil && s.Selection != nil && !s.Deployment.Deploys(s.Selection.Name, s.Selection.Dir)
+}
+
+// Drifted reports whether the provider location does not hold exactly the active environment.
+func (s *ProviderStatus) Drifted() bool {
+	if s.EnvironmentErr != nil {
+		return true
+	}
+	if s.Switched() {
+		return true
+	}
+	for _, f := range s.Files {
+		if f.Status != StatusInSync {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusOf returns the [ProviderStatus] of a given provider.
+func StatusOf(provider Provider) (*ProviderStatus, error) {
+	s := &ProviderStatus{
+		Provider: provider,
+	}
+
+	d, err := LoadDeployment(provider, ScopeGlobal)
+	if err != nil {
+		return nil, err
+	}
+	s.Deployment = d
+
+	if d != nil {
+		for _, f := range d.Files {
+			if f.Source == "" {
+				continue
+			}
+			st, err := f.Status()
+			if err != nil {
+				return nil, fmt.Errorf("status of %s: %w", f.Target, err)
+			}
+			s.Files = append(s.Files, FileState{Target: f.Target, Status: st})
+		}
+	}
+
+	dir, err := workingDir()
+	if err != nil {
+		return nil, err
+	}
+	sel, err := SelectEnvironment(provider, dir)
+	if err != nil {
+		s.EnvironmentErr = err
+		return s, nil
+	}
+	s.Selection = sel
+	s.Environment = sel.Name
+
+	env, err := sel.Environment()
+	if err != nil {
+		s.EnvironmentErr = err
+		return s, nil
+	}
+
+	// Report the files of the active environment which are not installed
+	if d == nil || d.Deploys(env.Name, env.Dir) {
+		files, err := env.Files()
+		if err != nil {
+			return nil, fmt.Errorf("list %s environment %s files: %w", provider, env.Name, err)
+		}
+		targetDir, err := TargetDir(provider)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range files {
+			target := filepath.Join(targetDir, name)
+			if slices.ContainsFunc(s.Files, func(f FileState) bool { return f.Target == target }) {
+				continue
+			}
+			s.Files = append(s.Files, FileState{Target: target, Status: StatusNotDeployed})
+		}
+	}
+
+	return s, nil
+}
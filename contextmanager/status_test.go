@@ -0,0 +1,251 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestDeployedFile_Status(t *testing.T) {
+	tests := map[string]struct {
+		// change changes the installed file at target or its managed source after the activation
+		change func(t *testing.T, target, source string)
+		want   map[contextmanager.Strategy]contextmanager.FileStatus
+	}{
+		"in-sync": {
+			change: func(t *testing.T, target, source string) {},
+			want: map[contextmanager.Strategy]contextmanager.FileStatus{
+				contextmanager.StrategySymlink:  contextmanager.StatusInSync,
+				contextmanager.StrategyHardlink: contextmanager.StatusInSync,
+				contextmanager.StrategyCopy:     contextmanager.StatusInSync,
+			},
+		},
+		"modified by the CLI": {
+			change: func(t *testing.T, target, source string) {
+				appendFile(t, target, "\n- remember this")
+			},
+			want: map[contextmanager.Strategy]contextmanager.FileStatus{
+				contextmanager.StrategySymlink:  contextmanager.StatusModified,
+				contextmanager.StrategyHardlink: contextmanager.StatusModified,
+				contextmanager.StrategyCopy:     contextmanager.StatusModified,
+			},
+		},
+		"rewritten in place by the CLI": {
+			change: func(t *testing.T, target, source string) {
+				if err := os.Remove(target); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(target, []byte("rewritten"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[contextmanager.Strategy]contextmanager.FileStatus{
+				contextmanager.StrategySymlink:  contextmanager.StatusModified,
+				contextmanager.StrategyHardlink: contextmanager.StatusModified,
+				contextmanager.StrategyCopy:     contextmanager.StatusModified,
+			},
+		},
+		"managed source changed": {
+			change: func(t *testing.T, target, source string) {
+				if err := os.Remove(source); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(source, []byte("new team rules"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[contextmanager.Strategy]contextmanager.FileStatus{
+				contextmanager.StrategySymlink:  contextmanager.StatusModified,
+				contextmanager.StrategyHardlink: contextmanager.StatusOutdated,
+				contextmanager.StrategyCopy:     contextmanager.StatusOutdated,
+			},
+		},
+		"managed source removed": {
+			change: func(t *testing.T, target, source string) {
+				if err := os.Remove(source); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[contextmanager.Strategy]contextmanager.FileStatus{
+				contextmanager.StrategySymlink:  contextmanager.StatusSourceMissing,
+				contextmanager.StrategyHardlink: contextmanager.StatusSourceMissing,
+				contextmanager.StrategyCopy:     contextmanager.StatusSourceMissing,
+			},
+		},
+		"missing": {
+			change: func(t *testing.T, target, source string) {
+				if err := os.Remove(target); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[contextmanager.Strategy]contextmanager.FileStatus{
+				contextmanager.StrategySymlink:  contextmanager.StatusMissing,
+				contextmanager.StrategyHardlink: contextmanager.StatusMissing,
+				contextmanager.StrategyCopy:     contextmanager.StatusMissing,
+			},
+		},
+		"replaced by an unmanaged symlink": {
+			change: func(t *testing.T, target, source string) {
+				other := filepath.Join(filepath.Dir(target), "other.md")
+				if err := os.WriteFile(other, []byte("other"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Remove(target); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(other, target); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[contextmanager.Strategy]contextmanager.FileStatus{
+				contextmanager.StrategySymlink:  contextmanager.StatusReplaced,
+				contextmanager.StrategyHardlink: contextmanager.StatusReplaced,
+				contextmanager.StrategyCopy:     contextmanager.StatusReplaced,
+			},
+		},
+		"replaced by a directory": {
+			change: func(t *testing.T, target, source string) {
+				if err := os.Remove(target); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Mkdir(target, 0o700); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[contextmanager.Strategy]contextmanager.FileStatus{
+				contextmanager.StrategySymlink:  contextmanager.StatusReplaced,
+				contextmanager.StrategyHardlink: contextmanager.StatusReplaced,
+				contextmanager.StrategyCopy:     contextmanager.StatusReplaced,
+			},
+		},
+	}
+	for name, tt := range tests {
+		for strategy, want := range tt.want {
+			t.Run(name+"/"+strategy.String(), func(t *testing.T) {
+				setupTestRoot(t)
+				setupTestHome(t)
+
+				work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "work"})
+				d, err := contextmanager.Activate(work, strategy)
+				if err != nil {
+					t.Fatalf("Activate() unexpected error: %v", err)
+				}
+				f := d.Files[0]
+				if f.Digest == "" {
+					t.Fatal("Activate() did not record the digest of the installed file")
+				}
+
+				tt.change(t, f.Target, f.Source)
+
+				got, err := f.Status()
+				if err != nil {
+					t.Fatalf("Status() unexpected error: %v", err)
+				}
+				if got != want {
+					t.Errorf("Status() = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+// appendFile appends content to the file at path in place.
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeactivate_SourceMissing(t *testing.T) {
+	for _, strategy := range contextmanager.Strategies() {
+		t.Run(strategy.String(), func(t *testing.T) {
+			setupTestRoot(t)
+			setupTestHome(t)
+
+			work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "work"})
+			d, err := contextmanager.Activate(work, strategy)
+			if err != nil {
+				t.Fatalf("Activate() unexpected error: %v", err)
+			}
+			if err := os.Remove(d.Files[0].Source); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err := contextmanager.Deactivate(contextmanager.ProviderClaudeCode, false); err != nil {
+				t.Fatalf("Deactivate() unexpected error: %v", err)
+			}
+			if _, err := os.Lstat(d.Files[0].Target); !os.IsNotExist(err) {
+				t.Errorf("installed file %s should be removed, got err = %v", d.Files[0].Target, err)
+			}
+		})
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "work"})
+	target := filepath.Join(home, ".claude", "CLAUDE.md")
+	if err := contextmanager.SetGlobalEnvironment(contextmanager.ProviderClaudeCode, "work"); err != nil {
+		t.Fatalf("SetGlobalEnvironment() unexpected error: %v", err)
+	}
+
+	s, err := contextmanager.StatusOf(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("StatusOf() unexpected error: %v", err)
+	}
+	if !s.Drifted() || len(s.Files) != 1 || s.Files[0].Status != contextmanager.StatusNotDeployed {
+		t.Errorf("StatusOf() before activation = %+v, want %s not deployed", s, target)
+	}
+
+	if _, err := contextmanager.Activate(work, contextmanager.StrategyCopy); err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	s, err = contextmanager.StatusOf(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("StatusOf() unexpected error: %v", err)
+	}
+	if s.Drifted() || s.Environment != "work" || len(s.Files) != 1 || s.Files[0].Status != contextmanager.StatusInSync {
+		t.Errorf("StatusOf() after activation = %+v, want %s in sync", s, target)
+	}
+
+	// Selecting another environment without activating it is a drift
+	if err := contextmanager.SetGlobalEnvironment(contextmanager.ProviderClaudeCode, contextmanager.DefaultEnvironment); err != nil {
+		t.Fatalf("SetGlobalEnvironment() unexpected error: %v", err)
+	}
+	s, err = contextmanager.StatusOf(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("StatusOf() unexpected error: %v", err)
+	}
+	if !s.Drifted() {
+		t.Errorf("StatusOf() = %+v, want drifted", s)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/zchee/llmctxenv/fileio"
+)
+
+// Strategy represents how the context files of an environment are installed into the provider locations.
+type Strategy string
+
+// String returns a string representation of the [Strategy] name.
+func (s Strategy) String() string { return string(s) }
+
+const (
+	// StrategySymlink installs a symbolic link pointing back into [LLMCtxEnvRoot].
+	StrategySymlink Strategy = "symlink"
+
+	// StrategyHardlink installs a hard link to the managed file. It requires the provider location and
+	// [LLMCtxEnvRoot] to be on the same filesystem.
+	StrategyHardlink Strategy = "hardlink"
+
+	// StrategyCopy installs a copy of the managed file. It works even if the provider CLI rewrites the file
+	// in place.
+	StrategyCopy Strategy = "copy"
+)
+
+// DefaultStrategy is the [Strategy] used when none is configured.
+const DefaultStrategy = StrategySymlink
+
+// Strategies returns all known strategies.
+func Strategies() []Strategy {
+	return []Strategy{StrategySymlink, StrategyHardlink, StrategyCopy}
+}
+
+// ParseStrategy parses s as a known [Strategy] name.
+func ParseStrategy(s string) (Strategy, error) {
+	switch st := Strategy(s); st {
+	case StrategySymlink, StrategyHardlink, StrategyCopy:
+		return st, nil
+	default:
+		return "", fmt.Errorf("unknown strategy %q", s)
+	}
+}
+
+// Install installs the source file into the target path.
+func (s Strategy) Install(target, source string) error {
+	switch s {
+	case StrategySymlink:
+		return os.Symlink(source, target)
+
+	case StrategyHardlink:
+		err := os.Link(source, target)
+		if errors.Is(err, syscall.EXDEV) {
+			return fmt.Errorf("%w: use the %q strategy for %s", err, StrategyCopy, target)
+		}
+		return err
+
+	case StrategyCopy:
+		fi, err := os.Stat(source)
+		if err != nil {
+			return err
+		}
+		return fileio.CopyFile(target, source, fi.Mode().Perm())
+
+	default:
+		return fmt.Errorf("unknown strategy %q", s)
+	}
+}
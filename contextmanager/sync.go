@@ -0,0 +1,231 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/zchee/llmctxenv/fileio"
+)
+
+// ErrSyncConflict is returned by [Sync] when the changes made to an installed file and its managed source
+// since the activation conflict.
+var ErrSyncConflict = errors.New("the changes to the installed file and the managed file conflict")
+
+// SyncAction represents what [Sync] does with an installed context file.
+type SyncAction string
+
+// String returns a string representation of the [SyncAction].
+func (a SyncAction) String() string { return string(a) }
+
+const (
+	// SyncUpToDate means the installed file and its managed source hold the same content.
+	SyncUpToDate SyncAction = "up-to-date"
+
+	// SyncPull means the changes made to the installed file, typically by the provider CLI, are copied back
+	// into the managed source.
+	SyncPull SyncAction = "pull"
+
+	// SyncPush means the changes made to the managed source are installed.
+	SyncPush SyncAction = "push"
+
+	// SyncMerge means both the installed file and its managed source have been changed, and the changes are
+	// merged into the managed source which is installed.
+	SyncMerge SyncAction = "merge"
+
+	// SyncConflict means the changes made to the installed file and its managed source conflict. The managed
+	// source holds the conflict markers and the installed file is left unchanged until [Resolve].
+	SyncConflict SyncAction = "conflict"
+
+	// SyncSkip means the installed file or its managed source is missing or has been replaced, which is
+	// left to [Activate] or [Deactivate].
+	SyncSkip SyncAction = "skip"
+)
+
+// SyncFile reports what [Sync] does with a single installed context file.
+type SyncFile struct {
+	Source string
+	Target string
+	Action SyncAction
+
+	// Status is the state of the file before the synchronization.
+	Status FileStatus
+}
+
+// Sync synchronizes the context files installed by [Activate] for a given provider with their managed
+// sources using a three-way comparison of the installed file, the managed source and the digest recorded at
+// the installation.
+//
+// Changes made only to the installed file are pulled into the managed source, changes made only to the
+// managed source are pushed to the provider location, changes made to both are merged, and the [Deployment]
+// records the synchronized digests. If any changes conflict, or a previous conflict has not been resolved
+// with [Resolve], it returns an error wrapping [ErrSyncConflict] after synchronizing the other files. If
+// dryRun is true, it only reports what would be done.
+//
+// It returns nil if the provider has not been activated.
+func Sync(provider Provider, dryRun bool) ([]SyncFile, error) {
+	d, err := LoadDeployment(provider, ScopeGlobal)
+	if err != nil || d == nil {
+		return nil, err
+	}
+
+	// Plan every file before changing anything
+	var files []SyncFile
+	for _, f := range d.Files {
+		if f.Source == "" {
+			continue
+		}
+		st, err := f.Status()
+		if err != nil {
+			return nil, fmt.Errorf("status of %s: %w", f.Target, err)
+		}
+		action, err := f.syncAction(st)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, SyncFile{Source: f.Source, Target: f.Target, Action: action, Status: st})
+	}
+	if dryRun {
+		return files, nil
+	}
+
+	var conflicts int
+	for i, sf := range files {
+		f := d.file(sf.Target)
+		var err error
+		switch sf.Action {
+		case SyncMerge:
+			var n int
+			if n, err = f.merge(d.Strategy); n > 0 {
+				files[i].Action = SyncConflict
+			}
+		case SyncPull:
+			err = f.pull(d.Strategy)
+		case SyncPush:
+			err = f.reinstall(d.Strategy)
+		case SyncUpToDate:
+			// Both sides may have been changed in the same way
+			f.Digest, err = snapshot(f.Source)
+		}
+		if err != nil {
+			return files, errors.Join(fmt.Errorf("%s %s: %w", sf.Action, sf.Target, err), d.save())
+		}
+		if files[i].Action == SyncConflict {
+			conflicts++
+		}
+	}
+	if err := d.save(); err != nil {
+		return files, err
+	}
+	if conflicts > 0 {
+		return files, ErrSyncConflict
+	}
+
+	return files, nil
+}
+
+// syncAction returns the [SyncAction] of f whose [FileStatus] is st.
+func (f DeployedFile) syncAction(st FileStatus) (SyncAction, error) {
+	switch st {
+	case StatusInSync:
+		return SyncUpToDate, nil
+	case StatusOutdated:
+		return SyncPush, nil
+	case StatusConflict:
+		return SyncConflict, nil
+	case StatusModified:
+		// Changes pulled into a rendered file would be lost by the next rendering, they are left installed
+		// until they are moved to the canonical file
+		if isWithin(f.Source, renderedRoot()) {
+			return SyncSkip, nil
+		}
+	default:
+		return SyncSkip, nil
+	}
+
+	// Changes made through a symbolic or hard link are already in the managed source
+	linked, err := f.linked()
+	if err != nil || linked {
+		return SyncPull, err
+	}
+
+	sourceDigest, err := fileio.HashFile(f.Source)
+	if err != nil {
+		return "", err
+	}
+	if sourceDigest == f.Digest {
+		return SyncPull, nil
+	}
+	// Refuse to merge before changing anything
+	if _, err := f.base(); err != nil {
+		return "", err
+	}
+	return SyncMerge, nil
+}
+
+// pull copies the installed file of f into its managed source and reinstalls it with the strategy, since a
+// provider CLI which rewrites the file replaces a symbolic or hard link with a regular file.
+func (f *DeployedFile) pull(strategy Strategy) error {
+	linked, err := f.linked()
+	if err != nil {
+		return err
+	}
+	if linked {
+		f.Digest, err = snapshot(f.Source)
+		return err
+	}
+
+	fi, err := os.Stat(f.Source)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(f.Source, f.Target, fi.Mode().Perm()); err != nil {
+		return fmt.Errorf("copy %s to %s: %w", f.Target, f.Source, err)
+	}
+
+	if strategy == StrategyCopy {
+		f.Digest, err = snapshot(f.Source)
+		return err
+	}
+	return f.reinstall(strategy)
+}
+
+// reinstall replaces the installed file of f with its managed source installed with the strategy.
+func (f *DeployedFile) reinstall(strategy Strategy) error {
+	tmp := stagingPath(f.Target)
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove %s: %w", tmp, err)
+	}
+	if err := strategy.Install(tmp, f.Source); err != nil {
+		return fmt.Errorf("install %s to %s: %w", f.Source, f.Target, err)
+	}
+	defer os.Remove(tmp)
+
+	digest, err := snapshot(tmp)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.Target); err != nil {
+		return fmt.Errorf("install %s to %s: %w", f.Source, f.Target, err)
+	}
+	f.Digest = digest
+
+	return nil
+}
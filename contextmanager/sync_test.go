@@ -0,0 +1,304 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+// rewriteFile replaces the file at path with a new file as a provider CLI writing atomically does.
+func rewriteFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	tests := map[string]struct {
+		// change changes the installed file at target or its managed source after the activation
+		change     func(t *testing.T, target, source string)
+		strategies []contextmanager.Strategy
+		want       contextmanager.SyncAction
+		content    string
+	}{
+		"up to date": {
+			change:     func(t *testing.T, target, source string) {},
+			strategies: contextmanager.Strategies(),
+			want:       contextmanager.SyncUpToDate,
+			content:    "rules\n",
+		},
+		"appended by the CLI": {
+			change: func(t *testing.T, target, source string) {
+				appendFile(t, target, "- memory\n")
+			},
+			strategies: contextmanager.Strategies(),
+			want:       contextmanager.SyncPull,
+			content:    "rules\n- memory\n",
+		},
+		"rewritten by the CLI": {
+			change: func(t *testing.T, target, source string) {
+				rewriteFile(t, target, "rules\n- memory\n")
+			},
+			strategies: contextmanager.Strategies(),
+			want:       contextmanager.SyncPull,
+			content:    "rules\n- memory\n",
+		},
+		"managed file changed": {
+			change: func(t *testing.T, target, source string) {
+				rewriteFile(t, source, "new rules\n")
+			},
+			strategies: []contextmanager.Strategy{contextmanager.StrategyHardlink, contextmanager.StrategyCopy},
+			want:       contextmanager.SyncPush,
+			content:    "new rules\n",
+		},
+		"changed in the same way": {
+			change: func(t *testing.T, target, source string) {
+				rewriteFile(t, source, "same\n")
+				rewriteFile(t, target, "same\n")
+			},
+			strategies: []contextmanager.Strategy{contextmanager.StrategyHardlink, contextmanager.StrategyCopy},
+			want:       contextmanager.SyncUpToDate,
+			content:    "same\n",
+		},
+		"changed both sides apart": {
+			change: func(t *testing.T, target, source string) {
+				rewriteFile(t, source, "# Team\nrules\n")
+				rewriteFile(t, target, "rules\n- memory\n")
+			},
+			strategies: []contextmanager.Strategy{contextmanager.StrategyHardlink, contextmanager.StrategyCopy},
+			want:       contextmanager.SyncMerge,
+			content:    "# Team\nrules\n- memory\n",
+		},
+	}
+	for name, tt := range tests {
+		for _, strategy := range tt.strategies {
+			t.Run(name+"/"+strategy.String(), func(t *testing.T) {
+				setupTestRoot(t)
+				setupTestHome(t)
+
+				work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "rules\n"})
+				d, err := contextmanager.Activate(work, strategy)
+				if err != nil {
+					t.Fatalf("Activate() unexpected error: %v", err)
+				}
+				target, source := d.Files[0].Target, d.Files[0].Source
+				tt.change(t, target, source)
+
+				files, err := contextmanager.Sync(contextmanager.ProviderClaudeCode, false)
+				if err != nil {
+					t.Fatalf("Sync() unexpected error: %v", err)
+				}
+				if len(files) != 1 || files[0].Action != tt.want {
+					t.Fatalf("Sync() = %+v, want %s", files, tt.want)
+				}
+
+				if got := readFile(t, source); got != tt.content {
+					t.Errorf("managed content = %q, want %q", got, tt.content)
+				}
+				if got := readFile(t, target); got != tt.content {
+					t.Errorf("installed content = %q, want %q", got, tt.content)
+				}
+
+				// The installed file is installed with the strategy again and recorded as in sync
+				fi, err := os.Lstat(target)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if isSymlink := fi.Mode()&os.ModeSymlink != 0; isSymlink != (strategy == contextmanager.StrategySymlink) {
+					t.Errorf("installed file mode = %v with %s strategy", fi.Mode(), strategy)
+				}
+				s, err := contextmanager.StatusOf(contextmanager.ProviderClaudeCode)
+				if err != nil {
+					t.Fatalf("StatusOf() unexpected error: %v", err)
+				}
+				if len(s.Files) != 1 || s.Files[0].Status != contextmanager.StatusInSync {
+					t.Errorf("StatusOf().Files = %+v, want in sync", s.Files)
+				}
+			})
+		}
+	}
+}
+
+func TestSync_Conflict(t *testing.T) {
+	for _, strategy := range []contextmanager.Strategy{contextmanager.StrategyHardlink, contextmanager.StrategyCopy} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			setupTestRoot(t)
+			setupTestHome(t)
+
+			work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "rules\n"})
+			d, err := contextmanager.Activate(work, strategy)
+			if err != nil {
+				t.Fatalf("Activate() unexpected error: %v", err)
+			}
+			target, source := d.Files[0].Target, d.Files[0].Source
+			rewriteFile(t, source, "managed\n")
+			rewriteFile(t, target, "installed\n")
+
+			// The conflict is written into the managed file and the installed file is left unchanged
+			files, err := contextmanager.Sync(contextmanager.ProviderClaudeCode, false)
+			if !errors.Is(err, contextmanager.ErrSyncConflict) {
+				t.Fatalf("Sync() error = %v, want %v", err, contextmanager.ErrSyncConflict)
+			}
+			if len(files) != 1 || files[0].Action != contextmanager.SyncConflict {
+				t.Errorf("Sync() = %+v, want a conflict", files)
+			}
+			merged := "<<<<<<< " + source + "\nmanaged\n=======\ninstalled\n>>>>>>> " + target + "\n"
+			if got := readFile(t, source); got != merged {
+				t.Errorf("managed content = %q, want %q", got, merged)
+			}
+			if got := readFile(t, target); got != "installed\n" {
+				t.Errorf("installed content = %q, want it unchanged", got)
+			}
+			s, err := contextmanager.StatusOf(contextmanager.ProviderClaudeCode)
+			if err != nil {
+				t.Fatalf("StatusOf() unexpected error: %v", err)
+			}
+			if len(s.Files) != 1 || s.Files[0].Status != contextmanager.StatusConflict {
+				t.Errorf("StatusOf().Files = %+v, want a conflict", s.Files)
+			}
+
+			// The conflict stays until it is resolved
+			if _, err := contextmanager.Sync(contextmanager.ProviderClaudeCode, false); !errors.Is(err, contextmanager.ErrSyncConflict) {
+				t.Errorf("Sync() again error = %v, want %v", err, contextmanager.ErrSyncConflict)
+			}
+			if got := readFile(t, source); got != merged {
+				t.Errorf("managed content after Sync() again = %q, want %q", got, merged)
+			}
+			if _, err := contextmanager.Activate(work, strategy); !errors.Is(err, contextmanager.ErrSyncConflict) {
+				t.Errorf("Activate() error = %v, want %v", err, contextmanager.ErrSyncConflict)
+			}
+			if _, err := contextmanager.Resolve(contextmanager.ProviderClaudeCode); !errors.Is(err, contextmanager.ErrConflictMarker) {
+				t.Errorf("Resolve() error = %v, want %v", err, contextmanager.ErrConflictMarker)
+			}
+
+			rewriteFile(t, source, "managed\ninstalled\n")
+			resolved, err := contextmanager.Resolve(contextmanager.ProviderClaudeCode)
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			if len(resolved) != 1 || resolved[0].Target != target || resolved[0].Conflicts != 0 {
+				t.Errorf("Resolve() = %+v, want %s resolved", resolved, target)
+			}
+			if got := readFile(t, target); got != "managed\ninstalled\n" {
+				t.Errorf("installed content = %q, want the resolved content", got)
+			}
+			s, err = contextmanager.StatusOf(contextmanager.ProviderClaudeCode)
+			if err != nil {
+				t.Fatalf("StatusOf() unexpected error: %v", err)
+			}
+			if len(s.Files) != 1 || s.Files[0].Status != contextmanager.StatusInSync {
+				t.Errorf("StatusOf().Files = %+v, want in sync", s.Files)
+			}
+		})
+	}
+}
+
+func TestResolve_ChangedAgain(t *testing.T) {
+	setupTestRoot(t)
+	setupTestHome(t)
+
+	work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "rules\n"})
+	d, err := contextmanager.Activate(work, contextmanager.StrategyCopy)
+	if err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	target, source := d.Files[0].Target, d.Files[0].Source
+	rewriteFile(t, source, "managed\n")
+	rewriteFile(t, target, "installed\n")
+	if _, err := contextmanager.Sync(contextmanager.ProviderClaudeCode, false); !errors.Is(err, contextmanager.ErrSyncConflict) {
+		t.Fatalf("Sync() error = %v, want %v", err, contextmanager.ErrSyncConflict)
+	}
+
+	// The CLI keeps adding memories to the installed file while the conflict is resolved
+	rewriteFile(t, source, "managed\ninstalled\n")
+	appendFile(t, target, "- memory\n")
+
+	resolved, err := contextmanager.Resolve(contextmanager.ProviderClaudeCode)
+	if err != nil {
+		t.Fatalf("Resolve() unexpected error: %v", err)
+	}
+	if len(resolved) != 1 || resolved[0].Conflicts != 0 {
+		t.Errorf("Resolve() = %+v, want resolved without conflicts", resolved)
+	}
+	want := "managed\ninstalled\n- memory\n"
+	if got := readFile(t, source); got != want {
+		t.Errorf("managed content = %q, want %q", got, want)
+	}
+	if got := readFile(t, target); got != want {
+		t.Errorf("installed content = %q, want %q", got, want)
+	}
+}
+
+func TestSync_DryRun(t *testing.T) {
+	setupTestRoot(t)
+	setupTestHome(t)
+
+	work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "rules"})
+	d, err := contextmanager.Activate(work, contextmanager.StrategyCopy)
+	if err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	appendFile(t, d.Files[0].Target, "\n- memory")
+
+	files, err := contextmanager.Sync(contextmanager.ProviderClaudeCode, true)
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0].Action != contextmanager.SyncPull {
+		t.Errorf("Sync() = %+v, want %s", files, contextmanager.SyncPull)
+	}
+	if got := readFile(t, d.Files[0].Source); got != "rules" {
+		t.Errorf("managed content = %q, want it unchanged", got)
+	}
+}
+
+func TestSync_NoMergeBase(t *testing.T) {
+	setupTestRoot(t)
+	setupTestHome(t)
+
+	work := createEnvironment(t, contextmanager.ProviderClaudeCode, "work", map[string]string{"CLAUDE.md": "rules\n"})
+	d, err := contextmanager.Activate(work, contextmanager.StrategyCopy)
+	if err != nil {
+		t.Fatalf("Activate() unexpected error: %v", err)
+	}
+	target, source := d.Files[0].Target, d.Files[0].Source
+	rewriteFile(t, source, "rules\nmanaged\n")
+	rewriteFile(t, target, "rules\ninstalled\n")
+	if err := os.RemoveAll(contextmanager.BaseDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Merging without the base would report every change as a conflict
+	if _, err := contextmanager.Sync(contextmanager.ProviderClaudeCode, false); !errors.Is(err, contextmanager.ErrNoMergeBase) {
+		t.Fatalf("Sync() error = %v, want %v", err, contextmanager.ErrNoMergeBase)
+	}
+	if got := readFile(t, source); got != "rules\nmanaged\n" {
+		t.Errorf("managed content = %q, want it unchanged", got)
+	}
+	if got := readFile(t, target); got != "rules\ninstalled\n" {
+		t.Errorf("installed content = %q, want it unchanged", got)
+	}
+}
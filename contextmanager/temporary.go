@@ -0,0 +1,83 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ActivateTemporarily activates the [Environment] with the given [Strategy] like [Activate], and returns the
+// function which restores the deployment of the provider as it was before.
+//
+// The restore function activates the previous environment again with its strategy, or deactivates the
+// provider if it had no deployment or the previous environment has been removed since. The installed files
+// changed while the environment was active, such as a copy edited by a command, are moved into [BackupDir]
+// first, so that the changes are neither lost nor prevent the restore; they are returned keyed by the target
+// path. If the activation fails after changing the deployment, the previous deployment is restored before
+// returning the error.
+func ActivateTemporarily(env *Environment, strategy Strategy) (restore func() (map[string]*Backup, error), err error) {
+	prev, err := LoadDeployment(env.Provider, ScopeGlobal)
+	if err != nil {
+		return nil, err
+	}
+
+	restore = func() (map[string]*Backup, error) {
+		if prev == nil {
+			_, saved, err := Deactivate(env.Provider, true)
+			return saved, err
+		}
+
+		var saved map[string]*Backup
+		cur, err := LoadDeployment(prev.Provider, ScopeGlobal)
+		if err != nil {
+			return nil, err
+		}
+		if cur != nil {
+			if saved, err = cur.saveModified(); err != nil {
+				return saved, err
+			}
+		}
+
+		env, err := prev.environment()
+		if err != nil {
+			if !errors.Is(err, ErrEnvironmentNotExist) {
+				return saved, err
+			}
+			_, _, err := Deactivate(prev.Provider, false)
+			return saved, err
+		}
+		if _, err := Activate(env, prev.Strategy); err != nil {
+			return saved, fmt.Errorf("restore %s environment %s: %w", prev.Provider, prev.Environment, err)
+		}
+		return saved, nil
+	}
+	if prev != nil && prev.Deploys(env.Name, env.Dir) && prev.Strategy == strategy {
+		// Nothing to switch, nor to restore
+		return func() (map[string]*Backup, error) { return nil, nil }, nil
+	}
+
+	if d, err := Activate(env, strategy); err != nil {
+		if d != nil {
+			// Nothing has run with the environment, so nothing is saved
+			_, rerr := restore()
+			err = errors.Join(err, rerr)
+		}
+		return nil, err
+	}
+	return restore, nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestActivateTemporarily(t *testing.T) {
+	tests := map[string]struct {
+		prev     string // environment activated before, if any
+		strategy contextmanager.Strategy
+		want     string // content of the target after restoring, empty if removed
+	}{
+		"Restore": {
+			prev:     "work",
+			strategy: contextmanager.StrategyCopy,
+			want:     "work",
+		},
+		"RestoreOtherStrategy": {
+			prev:     "work",
+			strategy: contextmanager.StrategySymlink,
+			want:     "work",
+		},
+		"Deactivate": {
+			strategy: contextmanager.StrategySymlink,
+		},
+		"Same": {
+			prev:     "review",
+			strategy: contextmanager.StrategyCopy,
+			want:     "review",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			setupTestRoot(t)
+			home := setupTestHome(t)
+
+			provider := contextmanager.ProviderClaudeCode
+			work := createEnvironment(t, provider, "work", map[string]string{"CLAUDE.md": "work"})
+			review := createEnvironment(t, provider, "review", map[string]string{"CLAUDE.md": "review"})
+			target := filepath.Join(home, ".claude", "CLAUDE.md")
+
+			var prev *contextmanager.Deployment
+			if tt.prev != "" {
+				env := map[string]*contextmanager.Environment{"work": work, "review": review}[tt.prev]
+				if _, err := contextmanager.Activate(env, contextmanager.StrategyCopy); err != nil {
+					t.Fatal(err)
+				}
+				var err error
+				if prev, err = contextmanager.LoadDeployment(provider, contextmanager.ScopeGlobal); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			restore, err := contextmanager.ActivateTemporarily(review, tt.strategy)
+			if err != nil {
+				t.Fatalf("ActivateTemporarily() unexpected error: %v", err)
+			}
+			if got := readFile(t, target); got != "review" {
+				t.Errorf("content while activated = %q, want %q", got, "review")
+			}
+
+			if _, err := restore(); err != nil {
+				t.Fatalf("restore() unexpected error: %v", err)
+			}
+			d, err := contextmanager.LoadDeployment(provider, contextmanager.ScopeGlobal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if _, err := os.Lstat(target); !errors.Is(err, os.ErrNotExist) || d != nil {
+					t.Errorf("after restore: deployment = %+v, target err = %v, want both removed", d, err)
+				}
+				return
+			}
+			if got := readFile(t, target); got != tt.want {
+				t.Errorf("content after restore = %q, want %q", got, tt.want)
+			}
+			if d == nil || d.Environment != prev.Environment || d.Strategy != prev.Strategy {
+				t.Errorf("deployment after restore = %+v, want %s by %s", d, prev.Environment, prev.Strategy)
+			}
+		})
+	}
+}
+
+func TestActivateTemporarily_RemovedEnvironment(t *testing.T) {
+	setupTestRoot(t)
+	home := setupTestHome(t)
+
+	provider := contextmanager.ProviderClaudeCode
+	work := createEnvironment(t, provider, "work", map[string]string{"CLAUDE.md": "work"})
+	review := createEnvironment(t, provider, "review", map[string]string{"CLAUDE.md": "review"})
+	if _, err := contextmanager.Activate(work, contextmanager.StrategySymlink); err != nil {
+		t.Fatal(err)
+	}
+
+	restore, err := contextmanager.ActivateTemporarily(review, contextmanager.StrategySymlink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(work.Dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// The previous environment is gone, so the provider is deactivated
+	if _, err := restore(); err != nil {
+		t.Fatalf("restore() unexpected error: %v", err)
+	}
+	target := filepath.Join(home, ".claude", "CLAUDE.md")
+	if _, err := os.Lstat(target); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("target %s should be removed, got err = %v", target, err)
+	}
+}
+
+func TestActivateTemporarily_Modified(t *testing.T) {
+	tests := map[string]struct {
+		prev string // environment activated before, if any
+		want string // content of the target after restoring, empty if removed
+	}{
+		"Restore": {
+			prev: "work",
+			want: "work",
+		},
+		"Deactivate": {},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			setupTestRoot(t)
+			home := setupTestHome(t)
+
+			provider := contextmanager.ProviderClaudeCode
+			work := createEnvironment(t, provider, "work", map[string]string{"CLAUDE.md": "work"})
+			review := createEnvironment(t, provider, "review", map[string]string{"CLAUDE.md": "review"})
+			target := filepath.Join(home, ".claude", "CLAUDE.md")
+			if tt.prev != "" {
+				if _, err := contextmanager.Activate(work, contextmanager.StrategySymlink); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			restore, err := contextmanager.ActivateTemporarily(review, contextmanager.StrategyCopy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The command edits the installed copy
+			if err := os.WriteFile(target, []byte("edited"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			saved, err := restore()
+			if err != nil {
+				t.Fatalf("restore() unexpected error: %v", err)
+			}
+			b, ok := saved[target]
+			if !ok || len(saved) != 1 {
+				t.Fatalf("restore() saved = %+v, want %s", saved, target)
+			}
+			if got := readFile(t, b.Path()); got != "edited" {
+				t.Errorf("saved content = %q, want %q", got, "edited")
+			}
+			if got := readFile(t, filepath.Join(review.Dir, "CLAUDE.md")); got != "review" {
+				t.Errorf("environment content = %q, want %q", got, "review")
+			}
+
+			if tt.want == "" {
+				if _, err := os.Lstat(target); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("target %s should be removed, got err = %v", target, err)
+				}
+				return
+			}
+			if got := readFile(t, target); got != tt.want {
+				t.Errorf("content after restore = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
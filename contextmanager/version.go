@@ -0,0 +1,217 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Version represents the contents of a version file which selects the environment of each [Provider].
+//
+// A version file is a line oriented text file. Each line is a "<provider>=<environment>" pair.
+// Empty lines and text following a '#' are ignored.
+//
+// A `[branch "<pattern>"]` line starts a [BranchRule]: the pairs following it select the environments
+// while the checked out git branch matches the pattern.
+//
+//	# team environments
+//	claude=review
+//	codex=work
+//
+//	[branch "release/*"]
+//	claude=release
+type Version struct {
+	// Providers maps a provider to its environment name.
+	Providers map[Provider]string
+
+	// Branches are the branch rules in the order of the file.
+	Branches []*BranchRule
+}
+
+// BranchRule selects the environments of the providers on the git branches whose name matches Pattern.
+type BranchRule struct {
+	// Pattern is a [path.Match] pattern of the branch name, so '*' does not match a '/'.
+	Pattern string
+
+	// Providers maps a provider to its environment name.
+	Providers map[Provider]string
+}
+
+// Match reports whether the branch name matches the rule.
+func (r *BranchRule) Match(branch string) bool {
+	ok, _ := path.Match(r.Pattern, branch)
+	return ok
+}
+
+// Select returns the environment name selected for a given provider on the branch, and the [BranchRule]
+// which selects it, or nil if no rule matches and the environment is selected unconditionally.
+//
+// The first matching rule which selects an environment for the provider takes precedence. An empty branch
+// matches no rule.
+func (v *Version) Select(provider Provider, branch string) (string, *BranchRule, bool) {
+	if branch != "" {
+		for _, r := range v.Branches {
+			if name, ok := r.Providers[provider]; ok && r.Match(branch) {
+				return name, r, true
+			}
+		}
+	}
+	name, ok := v.Lookup(provider)
+	return name, nil, ok
+}
+
+// Lookup returns the environment name selected for a given provider.
+func (v *Version) Lookup(provider Provider) (string, bool) {
+	name, ok := v.Providers[provider]
+	return name, ok
+}
+
+// Set selects the named environment for a given provider.
+func (v *Version) Set(provider Provider, name string) {
+	if v.Providers == nil {
+		v.Providers = make(map[Provider]string)
+	}
+	v.Providers[provider] = name
+}
+
+// Unset removes the environment selected for a given provider.
+func (v *Version) Unset(provider Provider) {
+	delete(v.Providers, provider)
+}
+
+// IsEmpty reports whether v selects no environment and has no branch rules.
+func (v *Version) IsEmpty() bool {
+	return len(v.Providers) == 0 && len(v.Branches) == 0
+}
+
+// ParseVersion parses the contents of a version file.
+func ParseVersion(data []byte) (*Version, error) {
+	v := &Version{}
+	providers := &v.Providers
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; sc.Scan(); lineno++ {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			r, err := parseBranchRule(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineno, err)
+			}
+			v.Branches = append(v.Branches, r)
+			providers = &r.Providers
+			continue
+		}
+
+		p, name, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: want <provider>=<environment>, got %q", lineno, line)
+		}
+		provider, name := Provider(strings.TrimSpace(p)), strings.TrimSpace(name)
+		if provider == "" {
+			return nil, fmt.Errorf("line %d: provider must be not empty", lineno)
+		}
+		if err := ValidateEnvironmentName(name); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineno, err)
+		}
+		if *providers == nil {
+			*providers = make(map[Provider]string)
+		}
+		(*providers)[provider] = name
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// parseBranchRule parses a `[branch "<pattern>"]` line.
+func parseBranchRule(line string) (*BranchRule, error) {
+	header, ok := strings.CutSuffix(line, "]")
+	if ok {
+		header, ok = strings.CutPrefix(header, "[branch ")
+	}
+	pattern, err := strconv.Unquote(strings.TrimSpace(header))
+	if !ok || err != nil {
+		return nil, fmt.Errorf("want [branch \"<pattern>\"], got %q", line)
+	}
+	if pattern == "" {
+		return nil, errors.New("branch pattern must be not empty")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("branch pattern %q: %w", pattern, err)
+	}
+
+	return &BranchRule{Pattern: pattern}, nil
+}
+
+// ReadVersionFile reads and parses the version file at path.
+func ReadVersionFile(path string) (*Version, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := ParseVersion(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// Bytes returns the contents of the version file representation of v.
+func (v *Version) Bytes() []byte {
+	var buf bytes.Buffer
+	writeProviders(&buf, v.Providers)
+	for _, r := range v.Branches {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(&buf, "[branch %s]\n", strconv.Quote(r.Pattern))
+		writeProviders(&buf, r.Providers)
+	}
+	return buf.Bytes()
+}
+
+func writeProviders(buf *bytes.Buffer, providers map[Provider]string) {
+	for _, p := range slices.Sorted(maps.Keys(providers)) {
+		fmt.Fprintf(buf, "%s=%s\n", p, providers[p])
+	}
+}
+
+// WriteVersionFile writes v to the version file at path.
+func WriteVersionFile(path string, v *Version) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("mkdir all %s path: %w", filepath.Dir(path), err)
+	}
+	return os.WriteFile(path, v.Bytes(), 0o644)
+}
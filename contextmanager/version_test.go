@@ -0,0 +1,205 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package contextmanager_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zchee/llmctxenv/contextmanager"
+)
+
+func TestParseVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		data    string
+		want    *contextmanager.Version
+		wantErr bool
+	}{
+		"empty": {
+			data: "",
+			want: &contextmanager.Version{},
+		},
+		"provider entries with comments": {
+			data: "# team environments\nclaude = review # code review\n\ncodex=oss\n",
+			want: &contextmanager.Version{
+				Providers: map[contextmanager.Provider]string{
+					contextmanager.ProviderClaudeCode: "review",
+					contextmanager.ProviderCodex:      "oss",
+				},
+			},
+		},
+		"branch rules": {
+			data: "claude=review\n\n[branch \"release/*\"]\nclaude=release\ncodex=release\n[branch \"main\"] # trunk\ncodex=oss\n",
+			want: &contextmanager.Version{
+				Providers: map[contextmanager.Provider]string{
+					contextmanager.ProviderClaudeCode: "review",
+				},
+				Branches: []*contextmanager.BranchRule{
+					{
+						Pattern: "release/*",
+						Providers: map[contextmanager.Provider]string{
+							contextmanager.ProviderClaudeCode: "release",
+							contextmanager.ProviderCodex:      "release",
+						},
+					},
+					{
+						Pattern: "main",
+						Providers: map[contextmanager.Provider]string{
+							contextmanager.ProviderCodex: "oss",
+						},
+					},
+				},
+			},
+		},
+		"unquoted branch pattern": {
+			data:    "[branch release/*]\n",
+			wantErr: true,
+		},
+		"invalid branch pattern": {
+			data:    "[branch \"release/[\"]\n",
+			wantErr: true,
+		},
+		"unknown section": {
+			data:    "[provider \"claude\"]\n",
+			wantErr: true,
+		},
+		"missing provider": {
+			data:    "work\n",
+			wantErr: true,
+		},
+		"empty provider": {
+			data:    "=work\n",
+			wantErr: true,
+		},
+		"invalid environment name": {
+			data:    "claude=../work\n",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := contextmanager.ParseVersion([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseVersion() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersion_Lookup(t *testing.T) {
+	t.Parallel()
+
+	v := &contextmanager.Version{}
+	if _, ok := v.Lookup(contextmanager.ProviderClaudeCode); ok {
+		t.Error("Lookup() on empty version should not find an environment")
+	}
+
+	v.Set(contextmanager.ProviderClaudeCode, "review")
+
+	if got, ok := v.Lookup(contextmanager.ProviderClaudeCode); !ok || got != "review" {
+		t.Errorf("Lookup(claude) = %q, %v, want %q", got, ok, "review")
+	}
+	if got, ok := v.Lookup(contextmanager.ProviderCodex); ok {
+		t.Errorf("Lookup(codex) = %q, want no environment", got)
+	}
+
+	v.Unset(contextmanager.ProviderClaudeCode)
+	if got, ok := v.Lookup(contextmanager.ProviderClaudeCode); ok || !v.IsEmpty() {
+		t.Errorf("Lookup(claude) after Unset = %q, want no environment", got)
+	}
+}
+
+func TestVersion_Select(t *testing.T) {
+	t.Parallel()
+
+	v, err := contextmanager.ParseVersion([]byte("claude=review\n[branch \"release/*\"]\nclaude=release\n[branch \"*\"]\nclaude=topic\ncodex=topic\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		provider contextmanager.Provider
+		branch   string
+		want     string
+		wantRule string
+		wantOK   bool
+	}{
+		"first matching rule":     {provider: contextmanager.ProviderClaudeCode, branch: "release/1.2", want: "release", wantRule: "release/*", wantOK: true},
+		"later rule":              {provider: contextmanager.ProviderClaudeCode, branch: "feature", want: "topic", wantRule: "*", wantOK: true},
+		"star does not match '/'": {provider: contextmanager.ProviderClaudeCode, branch: "feature/x", want: "review", wantOK: true},
+		"no branch":               {provider: contextmanager.ProviderClaudeCode, want: "review", wantOK: true},
+		"rule of other provider":  {provider: contextmanager.ProviderCodex, branch: "release/1.2"},
+		"rule only":               {provider: contextmanager.ProviderCodex, branch: "main", want: "topic", wantRule: "*", wantOK: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, rule, ok := v.Select(tt.provider, tt.branch)
+			var gotRule string
+			if rule != nil {
+				gotRule = rule.Pattern
+			}
+			if got != tt.want || gotRule != tt.wantRule || ok != tt.wantOK {
+				t.Errorf("Select(%v, %q) = %q, %q, %t, want %q, %q, %t", tt.provider, tt.branch, got, gotRule, ok, tt.want, tt.wantRule, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestWriteVersionFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sub", "version")
+	want := &contextmanager.Version{
+		Providers: map[contextmanager.Provider]string{
+			contextmanager.ProviderGeminiCLI:  "oss",
+			contextmanager.ProviderClaudeCode: "review",
+		},
+		Branches: []*contextmanager.BranchRule{
+			{
+				Pattern:   "release/*",
+				Providers: map[contextmanager.Provider]string{contextmanager.ProviderClaudeCode: "release"},
+			},
+		},
+	}
+	if err := contextmanager.WriteVersionFile(path, want); err != nil {
+		t.Fatalf("WriteVersionFile() unexpected error: %v", err)
+	}
+
+	got, err := contextmanager.ReadVersionFile(path)
+	if err != nil {
+		t.Fatalf("ReadVersionFile() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadVersionFile() = %+v, want %+v", got, want)
+	}
+	if got, want := string(want.Bytes()), "claude=review\ngemini-cli=oss\n\n[branch \"release/*\"]\nclaude=release\n"; got != want {
+		t.Errorf("Bytes() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gitrepo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Config represents the contents of a git configuration file.
+//
+// Section and key names are case-insensitive, subsection names are case-sensitive. Includes are not
+// followed.
+type Config struct {
+	values map[string][]string
+}
+
+// ParseConfig parses the contents of a git configuration file.
+func ParseConfig(data []byte) (*Config, error) {
+	c := &Config{values: make(map[string][]string)}
+
+	var section string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; sc.Scan(); lineno++ {
+		line := strings.TrimSpace(sc.Text())
+		// Join the continuation lines
+		for strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`) && sc.Scan() {
+			lineno++
+			line = line[:len(line)-1] + sc.Text()
+		}
+
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			s, rest, err := parseSection(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineno, err)
+			}
+			section = s
+			line = strings.TrimSpace(rest)
+			if line == "" || line[0] == '#' || line[0] == ';' {
+				continue
+			}
+		}
+		if section == "" {
+			return nil, fmt.Errorf("line %d: key outside of a section", lineno)
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			return nil, fmt.Errorf("line %d: key must be not empty", lineno)
+		}
+		if !ok {
+			// A key without a value is a boolean true
+			value = "true"
+		} else {
+			v, err := parseValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineno, err)
+			}
+			value = v
+		}
+		name := section + "." + key
+		c.values[name] = append(c.values[name], value)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// parseSection parses the section header at the start of line, and returns the section name in the
+// "<section>\x00<subsection>" form and the rest of the line.
+func parseSection(line string) (string, string, error) {
+	quote := strings.IndexByte(line, '"')
+	end := strings.IndexByte(line, ']')
+	if end < 0 {
+		return "", "", fmt.Errorf("unterminated section header %q", line)
+	}
+
+	if quote < 0 || quote > end {
+		// The deprecated [section.subsection] form has a case-insensitive subsection
+		name, sub, _ := strings.Cut(strings.TrimSpace(line[1:end]), ".")
+		return strings.ToLower(name) + "\x00" + strings.ToLower(sub), line[end+1:], nil
+	}
+
+	name := strings.TrimSpace(line[1:quote])
+	var sub strings.Builder
+	for i := quote + 1; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '\\':
+			i++
+			if i < len(line) {
+				sub.WriteByte(line[i])
+			}
+		case '"':
+			rest, ok := strings.CutPrefix(line[i+1:], "]")
+			if !ok {
+				return "", "", fmt.Errorf("invalid section header %q", line)
+			}
+			return strings.ToLower(name) + "\x00" + sub.String(), rest, nil
+		default:
+			sub.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated subsection in %q", line)
+}
+
+// parseValue parses a value, removing the quotes, escapes and trailing comment.
+func parseValue(s string) (string, error) {
+	var b strings.Builder
+	var quoted bool
+	var space int // pending unquoted spaces, which are dropped at the end of the value
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+			continue
+		case c == '\\':
+			i++
+			if i == len(s) {
+				return "", fmt.Errorf("invalid escape at the end of %q", s)
+			}
+			switch s[i] {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case '"', '\\':
+				c = s[i]
+			default:
+				return "", fmt.Errorf("invalid escape %q in %q", s[i-1:i+1], s)
+			}
+		case !quoted && (c == '#' || c == ';'):
+			i = len(s)
+			continue
+		case !quoted && (c == ' ' || c == '\t'):
+			if b.Len() > 0 {
+				space++
+			}
+			continue
+		}
+		for ; space > 0; space-- {
+			b.WriteByte(' ')
+		}
+		b.WriteByte(c)
+	}
+	if quoted {
+		return "", fmt.Errorf("unterminated quote in %q", s)
+	}
+	return b.String(), nil
+}
+
+// Get returns the last value of the key in the section and subsection.
+func (c *Config) Get(section, subsection, key string) (string, bool) {
+	values := c.values[strings.ToLower(section)+"\x00"+subsection+"."+strings.ToLower(key)]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
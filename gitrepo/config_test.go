@@ -0,0 +1,96 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gitrepo_test
+
+import (
+	"testing"
+
+	"github.com/zchee/llmctxenv/gitrepo"
+)
+
+func TestParseConfig(t *testing.T) {
+	t.Parallel()
+
+	data := `# global comment
+[core]
+	repositoryformatversion = 0
+	bare = false
+	logallrefupdates
+[remote "origin"]
+	url = git@github.com:zchee/llmctxenv.git ; trailing comment
+	fetch = +refs/heads/*:refs/remotes/origin/*
+[remote "Up \"stream\""]
+	URL = "https://example.com/a b.git" # quoted
+[branch "main"]
+	remote = origin
+	merge = refs/heads/main
+[Remote.Legacy]
+	url = https://example.com/legacy.git
+[alias]
+	lg = log --oneline \
+		--graph
+[remote "origin"]
+	url = https://github.com/zchee/llmctxenv
+`
+	cfg, err := gitrepo.ParseConfig([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		section, subsection, key string
+		want                     string
+		wantOK                   bool
+	}{
+		{"core", "", "bare", "false", true},
+		{"CORE", "", "LogAllRefUpdates", "true", true},
+		{"remote", "origin", "url", "https://github.com/zchee/llmctxenv", true},
+		{"remote", "origin", "fetch", "+refs/heads/*:refs/remotes/origin/*", true},
+		{"remote", `Up "stream"`, "url", "https://example.com/a b.git", true},
+		{"remote", "up \"stream\"", "url", "", false},
+		{"remote", "legacy", "url", "https://example.com/legacy.git", true},
+		{"alias", "", "lg", "log --oneline   --graph", true}, // each unquoted whitespace is a space as in git
+		{"remote", "upstream", "url", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := cfg.Get(tt.section, tt.subsection, tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Get(%q, %q, %q) = %q, %t, want %q, %t", tt.section, tt.subsection, tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestParseConfig_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"key outside section":     "url = x\n",
+		"unterminated section":    "[remote \"origin\"\n",
+		"unterminated quote":      "[core]\n\tkey = \"value\n",
+		"invalid escape":          "[core]\n\tkey = a\\qb\n",
+		"unterminated subsection": "[remote \"origin]\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := gitrepo.ParseConfig([]byte(data)); err == nil {
+				t.Errorf("ParseConfig(%q) expected error but got none", data)
+			}
+		})
+	}
+}
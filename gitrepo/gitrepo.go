@@ -0,0 +1,159 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gitrepo reads the metadata of git repositories directly from the file system, without running the
+// git command.
+package gitrepo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotRepository is returned when a directory is not in a git working tree.
+var ErrNotRepository = errors.New("not a git repository")
+
+// Repo represents a git working tree.
+type Repo struct {
+	// WorkTree is the top-level directory of the working tree.
+	WorkTree string
+
+	// GitDir is the git directory of the working tree. It is the ".git" directory of the main working tree,
+	// and the directory ".git" file points to in a linked working tree created by "git worktree add" or a
+	// submodule.
+	GitDir string
+
+	// CommonDir is the git directory shared by the main working tree and its linked working trees, which
+	// holds the configuration.
+	CommonDir string
+}
+
+// Open returns the [Repo] whose top-level directory is dir.
+//
+// It returns an error wrapping [ErrNotRepository] if dir has no ".git" directory or file.
+func Open(dir string) (*Repo, error) {
+	dotgit := filepath.Join(dir, ".git")
+	fi, err := os.Stat(dotgit)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", dir, ErrNotRepository)
+		}
+		return nil, err
+	}
+
+	r := &Repo{
+		WorkTree: dir,
+		GitDir:   dotgit,
+	}
+	if !fi.IsDir() {
+		gitdir, err := readPointer(dotgit, "gitdir")
+		if err != nil {
+			return nil, err
+		}
+		r.GitDir = gitdir
+	}
+
+	r.CommonDir = r.GitDir
+	if commondir, err := os.ReadFile(filepath.Join(r.GitDir, "commondir")); err == nil {
+		r.CommonDir = resolve(r.GitDir, string(bytes.TrimSpace(commondir)))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Find returns the [Repo] of the working tree which contains dir.
+//
+// It returns an error wrapping [ErrNotRepository] if neither dir nor its parents are in a working tree.
+func Find(dir string) (*Repo, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for d := dir; ; {
+		r, err := Open(d)
+		if !errors.Is(err, ErrNotRepository) {
+			return r, err
+		}
+
+		parent := filepath.Dir(d)
+		if parent == d {
+			return nil, fmt.Errorf("%s: %w", dir, ErrNotRepository)
+		}
+		d = parent
+	}
+}
+
+// Config returns the configuration of the repository.
+func (r *Repo) Config() (*Config, error) {
+	path := filepath.Join(r.CommonDir, "config")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// RemoteURL returns the URL of the named remote.
+//
+// It returns false if the remote has no URL.
+func (r *Repo) RemoteURL(name string) (string, bool, error) {
+	cfg, err := r.Config()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	url, ok := cfg.Get("remote", name, "url")
+	return url, ok, nil
+}
+
+// readPointer reads the file at path holding a "<key>: <path>" line, such as the ".git" file of a linked
+// working tree, and returns the path resolved relative to the directory of the file.
+func readPointer(path, key string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	target, ok := strings.CutPrefix(string(bytes.TrimSpace(data)), key+":")
+	if !ok {
+		return "", fmt.Errorf("%s: want %q line, got %q", path, key+": <path>", data)
+	}
+	return resolve(filepath.Dir(path), strings.TrimSpace(target)), nil
+}
+
+// resolve returns path resolved relative to dir.
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
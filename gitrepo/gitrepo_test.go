@@ -0,0 +1,165 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gitrepo_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zchee/llmctxenv/gitrepo"
+)
+
+// writeFile writes content to the file at path creating its parent directories.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const originConfig = "[remote \"origin\"]\n\turl = git@github.com:zchee/llmctxenv.git\n"
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	main := filepath.Join(tmp, "main")
+	writeFile(t, filepath.Join(main, ".git", "config"), originConfig)
+	writeFile(t, filepath.Join(main, ".git", "HEAD"), "ref: refs/heads/main\n")
+
+	// A linked working tree as created by "git worktree add ../wt"
+	wt := filepath.Join(tmp, "wt")
+	wtGitDir := filepath.Join(main, ".git", "worktrees", "wt")
+	writeFile(t, filepath.Join(wt, ".git"), "gitdir: "+wtGitDir+"\n")
+	writeFile(t, filepath.Join(wtGitDir, "commondir"), "../..\n")
+
+	// A submodule whose git directory is relative to the working tree
+	sub := filepath.Join(main, "third_party", "sub")
+	writeFile(t, filepath.Join(sub, ".git"), "gitdir: ../../.git/modules/sub\n")
+	writeFile(t, filepath.Join(main, ".git", "modules", "sub", "config"), "[remote \"origin\"]\n\turl = https://example.com/sub\n")
+
+	tests := map[string]struct {
+		dir           string
+		wantWorkTree  string
+		wantGitDir    string
+		wantCommonDir string
+		wantURL       string
+	}{
+		"main": {
+			dir:           filepath.Join(main, "a", "b"),
+			wantWorkTree:  main,
+			wantGitDir:    filepath.Join(main, ".git"),
+			wantCommonDir: filepath.Join(main, ".git"),
+			wantURL:       "git@github.com:zchee/llmctxenv.git",
+		},
+		"linked worktree": {
+			dir:           wt,
+			wantWorkTree:  wt,
+			wantGitDir:    wtGitDir,
+			wantCommonDir: filepath.Join(main, ".git"),
+			wantURL:       "git@github.com:zchee/llmctxenv.git",
+		},
+		"submodule": {
+			dir:           filepath.Join(sub, "pkg"),
+			wantWorkTree:  sub,
+			wantGitDir:    filepath.Join(main, ".git", "modules", "sub"),
+			wantCommonDir: filepath.Join(main, ".git", "modules", "sub"),
+			wantURL:       "https://example.com/sub",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := os.MkdirAll(tt.dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			r, err := gitrepo.Find(tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.WorkTree != tt.wantWorkTree || r.GitDir != tt.wantGitDir || r.CommonDir != tt.wantCommonDir {
+				t.Errorf("Find(%q) = %+v", tt.dir, r)
+			}
+			url, ok, err := r.RemoteURL("origin")
+			if err != nil || !ok || url != tt.wantURL {
+				t.Errorf("RemoteURL() = %q, %t, %v, want %q", url, ok, err, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestFind_NotRepository(t *testing.T) {
+	t.Parallel()
+
+	if _, err := gitrepo.Find(t.TempDir()); !errors.Is(err, gitrepo.ErrNotRepository) {
+		t.Errorf("Find() error = %v, want %v", err, gitrepo.ErrNotRepository)
+	}
+}
+
+func TestRemoteURL_NoRemote(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), "ref: refs/heads/main\n")
+
+	r, err := gitrepo.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url, ok, err := r.RemoteURL("origin"); err != nil || ok {
+		t.Errorf("RemoteURL() = %q, %t, %v, want no remote", url, ok, err)
+	}
+}
+
+func TestBranch(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		head   string
+		want   string
+		wantOK bool
+	}{
+		"branch":       {head: "ref: refs/heads/main\n", want: "main", wantOK: true},
+		"slash":        {head: "ref: refs/heads/release/1.2\n", want: "release/1.2", wantOK: true},
+		"unborn":       {head: "ref: refs/heads/topic", want: "topic", wantOK: true},
+		"detached":     {head: "0123456789abcdef0123456789abcdef01234567\n"},
+		"non-head ref": {head: "ref: refs/remotes/origin/main\n"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, ".git", "HEAD"), tt.head)
+			r, err := gitrepo.Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok, err := r.Branch()
+			if err != nil || got != tt.want || ok != tt.wantOK {
+				t.Errorf("Branch() = %q, %t, %v, want %q, %t", got, ok, err, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gitrepo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Branch returns the name of the branch checked out in the working tree, read from the HEAD file of
+// [Repo.GitDir].
+//
+// It returns false if HEAD is detached.
+func (r *Repo) Branch() (string, bool, error) {
+	data, err := os.ReadFile(filepath.Join(r.GitDir, "HEAD"))
+	if err != nil {
+		return "", false, err
+	}
+
+	ref, ok := strings.CutPrefix(string(bytes.TrimSpace(data)), "ref:")
+	if !ok {
+		return "", false, nil
+	}
+	branch, ok := strings.CutPrefix(strings.TrimSpace(ref), "refs/heads/")
+	if !ok {
+		return "", false, nil
+	}
+	return branch, true, nil
+}
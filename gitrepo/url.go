@@ -0,0 +1,78 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gitrepo
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// NormalizeURL returns the canonical "<host>/<path>" form of a remote URL, so that the URLs of a repository
+// using different protocols or credentials are equal.
+//
+// The scheme, user, port and ".git" suffix are removed and the host is lower-cased:
+//
+//	git@github.com:zchee/llmctxenv.git      -> github.com/zchee/llmctxenv
+//	https://github.com/zchee/llmctxenv      -> github.com/zchee/llmctxenv
+//	ssh://git@GitHub.com:22/zchee/llmctxenv -> github.com/zchee/llmctxenv
+//
+// The URL of a local repository is normalized to its cleaned path.
+func NormalizeURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", errors.New("empty remote URL")
+	}
+
+	var host, p string
+	switch {
+	case strings.Contains(rawURL, "://"):
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return "", fmt.Errorf("parse remote URL: %w", err)
+		}
+		host, p = u.Hostname(), u.Path
+	case isSCPLike(rawURL):
+		// [user@]host:path
+		hostpart, pathpart, _ := strings.Cut(rawURL, ":")
+		if i := strings.LastIndexByte(hostpart, '@'); i >= 0 {
+			hostpart = hostpart[i+1:]
+		}
+		host, p = hostpart, pathpart
+	default:
+		p = rawURL
+	}
+
+	p = strings.TrimSuffix(strings.Trim(path.Clean("/"+p), "/"), ".git")
+	if host == "" {
+		return "/" + p, nil
+	}
+	if p == "" {
+		return "", fmt.Errorf("remote URL %q has no path", rawURL)
+	}
+	return strings.ToLower(host) + "/" + p, nil
+}
+
+// isSCPLike reports whether s is a scp-like "[user@]host:path" address. As in git, a colon after the first
+// slash is a part of a local path.
+func isSCPLike(s string) bool {
+	colon := strings.IndexByte(s, ':')
+	slash := strings.IndexByte(s, '/')
+	return colon > 0 && (slash < 0 || colon < slash)
+}
@@ -18,6 +18,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -26,7 +27,14 @@ import (
 
 func main() {
 	if err := cmd.New().Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		// The command has reported its result by itself
+		var exitErr *cmd.ExitError
+		if !errors.As(err, &exitErr) || err != error(exitErr) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		if exitErr != nil {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,156 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package merge implements a line oriented three-way merge of text files.
+package merge
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/zchee/llmctxenv/textdiff"
+)
+
+// Conflict markers written by [Merge] around the lines which cannot be merged automatically.
+const (
+	MarkerOurs   = "<<<<<<<"
+	MarkerSep    = "======="
+	MarkerTheirs = ">>>>>>>"
+)
+
+// Labels name the sides of a merge in the conflict markers.
+type Labels struct {
+	Ours   string
+	Theirs string
+}
+
+// Result is the result of [Merge].
+type Result struct {
+	// Text is the merged text, which holds conflict markers if Conflicts is not zero.
+	Text string
+
+	// Conflicts is the number of conflicts which could not be merged automatically.
+	Conflicts int
+}
+
+// Merge merges the changes made from base to ours and from base to theirs with the diff3 algorithm.
+//
+// A change made on only one side is taken as it is, and the same change made on both sides is taken once.
+// Different changes made to the same lines are conflicts written between git style conflict markers:
+//
+//	<<<<<<< ours
+//	lines of ours
+//	=======
+//	lines of theirs
+//	>>>>>>> theirs
+func Merge(base, ours, theirs string, labels Labels) Result {
+	b, o, t := textdiff.Lines(base), textdiff.Lines(ours), textdiff.Lines(theirs)
+	matchO, matchT := matches(b, o), matches(b, t)
+
+	var res Result
+	var sb strings.Builder
+	i, oi, ti := 0, 0, 0
+	for {
+		// Copy the lines which are unchanged on both sides
+		for i < len(b) && matchO[i] == oi && matchT[i] == ti {
+			sb.WriteString(b[i])
+			i, oi, ti = i+1, oi+1, ti+1
+		}
+		if i == len(b) && oi == len(o) && ti == len(t) {
+			break
+		}
+
+		// The changed chunk ends at the next base line which is kept on both sides
+		j, oj, tj := i, len(o), len(t)
+		for ; j < len(b); j++ {
+			if matchO[j] >= 0 && matchT[j] >= 0 {
+				oj, tj = matchO[j], matchT[j]
+				break
+			}
+		}
+
+		chunkB, chunkO, chunkT := b[i:j], o[oi:oj], t[ti:tj]
+		switch {
+		case slices.Equal(chunkO, chunkB):
+			writeLines(&sb, chunkT)
+		case slices.Equal(chunkT, chunkB), slices.Equal(chunkO, chunkT):
+			writeLines(&sb, chunkO)
+		default:
+			res.Conflicts++
+			sb.WriteString(MarkerOurs + label(labels.Ours))
+			writeLines(&sb, terminate(chunkO))
+			sb.WriteString(MarkerSep + "\n")
+			writeLines(&sb, terminate(chunkT))
+			sb.WriteString(MarkerTheirs + label(labels.Theirs))
+		}
+		i, oi, ti = j, oj, tj
+	}
+	res.Text = sb.String()
+
+	return res
+}
+
+// matches returns the index of the line of b which is kept from each line of a, or -1 if it is changed.
+func matches(a, b []string) []int {
+	m := make([]int, len(a))
+	for i := range m {
+		m[i] = -1
+	}
+	for _, op := range textdiff.Diff(a, b) {
+		if op.Kind != textdiff.Equal {
+			continue
+		}
+		for k := range op.A1 - op.A0 {
+			m[op.A0+k] = op.B0 + k
+		}
+	}
+	return m
+}
+
+func writeLines(sb *strings.Builder, lines []string) {
+	for _, line := range lines {
+		sb.WriteString(line)
+	}
+}
+
+// terminate returns lines whose last line ends with a newline, so that a conflict marker can follow it.
+func terminate(lines []string) []string {
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines = append(lines[:n-1:n-1], lines[n-1]+"\n")
+	}
+	return lines
+}
+
+func label(s string) string {
+	if s == "" {
+		return "\n"
+	}
+	return " " + s + "\n"
+}
+
+// FindConflictMarker returns the 1-based line number of the first conflict marker written by [Merge] in
+// text, or 0 if there is none.
+func FindConflictMarker(text string) int {
+	for i, line := range textdiff.Lines(text) {
+		line = strings.TrimRight(line, "\r\n")
+		for _, marker := range []string{MarkerOurs, MarkerTheirs} {
+			if line == marker || strings.HasPrefix(line, marker+" ") {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
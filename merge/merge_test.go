@@ -0,0 +1,136 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package merge_test
+
+import (
+	"testing"
+
+	"github.com/zchee/llmctxenv/merge"
+)
+
+func TestMerge(t *testing.T) {
+	const base = "# Rules\n\n- a\n- b\n- c\n"
+
+	tests := map[string]struct {
+		ours, theirs  string
+		want          string
+		wantConflicts int
+	}{
+		"unchanged": {
+			ours:   base,
+			theirs: base,
+			want:   base,
+		},
+		"changed ours": {
+			ours:   "# Rules\n\n- a\n- B\n- c\n",
+			theirs: base,
+			want:   "# Rules\n\n- a\n- B\n- c\n",
+		},
+		"changed theirs": {
+			ours:   base,
+			theirs: base + "- memory\n",
+			want:   base + "- memory\n",
+		},
+		"changed both sides apart": {
+			ours:   "# Team rules\n\n- a\n- b\n- c\n",
+			theirs: base + "- memory\n",
+			want:   "# Team rules\n\n- a\n- b\n- c\n- memory\n",
+		},
+		"same change on both sides": {
+			ours:   "# Rules\n\n- a\n- c\n",
+			theirs: "# Rules\n\n- a\n- c\n",
+			want:   "# Rules\n\n- a\n- c\n",
+		},
+		"deleted ours and appended theirs": {
+			ours:   "# Rules\n\n- b\n- c\n",
+			theirs: base + "- memory\n",
+			want:   "# Rules\n\n- b\n- c\n- memory\n",
+		},
+		"conflict": {
+			ours:          "# Rules\n\n- a\n- ours\n- c\n",
+			theirs:        "# Rules\n\n- a\n- theirs\n- c\n",
+			want:          "# Rules\n\n- a\n<<<<<<< managed\n- ours\n=======\n- theirs\n>>>>>>> installed\n- c\n",
+			wantConflicts: 1,
+		},
+		"conflict at the end": {
+			ours:          base + "- ours\n",
+			theirs:        base + "- theirs",
+			want:          base + "<<<<<<< managed\n- ours\n=======\n- theirs\n>>>>>>> installed\n",
+			wantConflicts: 1,
+		},
+		"conflicts and merged changes": {
+			ours:          "# Ours\n\n- a\n- b\n- c\n- d\n",
+			theirs:        "# Theirs\n\n- a\n- b\n- c\n- e\n",
+			want:          "<<<<<<< managed\n# Ours\n=======\n# Theirs\n>>>>>>> installed\n\n- a\n- b\n- c\n<<<<<<< managed\n- d\n=======\n- e\n>>>>>>> installed\n",
+			wantConflicts: 2,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := merge.Merge(base, tt.ours, tt.theirs, merge.Labels{Ours: "managed", Theirs: "installed"})
+			if got.Text != tt.want || got.Conflicts != tt.wantConflicts {
+				t.Errorf("Merge() = %d conflicts\n%s\nwant %d conflicts\n%s", got.Conflicts, got.Text, tt.wantConflicts, tt.want)
+			}
+			if line := merge.FindConflictMarker(got.Text); (line != 0) != (tt.wantConflicts != 0) {
+				t.Errorf("FindConflictMarker() = %d with %d conflicts", line, got.Conflicts)
+			}
+		})
+	}
+}
+
+func TestMerge_EmptyBase(t *testing.T) {
+	got := merge.Merge("", "same\nours\n", "same\ntheirs\n", merge.Labels{})
+	want := "<<<<<<<\nsame\nours\n=======\nsame\ntheirs\n>>>>>>>\n"
+	if got.Text != want || got.Conflicts != 1 {
+		t.Errorf("Merge() = %d conflicts\n%s\nwant 1 conflict\n%s", got.Conflicts, got.Text, want)
+	}
+}
+
+func TestFindConflictMarker(t *testing.T) {
+	tests := map[string]struct {
+		text string
+		want int
+	}{
+		"none": {
+			text: "# Title\n\ntext\n",
+			want: 0,
+		},
+		"setext heading": {
+			text: "Title\n=======\n",
+			want: 0,
+		},
+		"ours": {
+			text: "a\n<<<<<<< managed\nb\n",
+			want: 2,
+		},
+		"theirs without label": {
+			text: "a\nb\n>>>>>>>",
+			want: 3,
+		},
+		"longer run": {
+			text: "<<<<<<<< not a marker\n",
+			want: 0,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := merge.FindConflictMarker(tt.text); got != tt.want {
+				t.Errorf("FindConflictMarker(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
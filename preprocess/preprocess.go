@@ -0,0 +1,194 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package preprocess implements the provider conditional sections of context files.
+//
+// A section is kept only in the context files of the providers its condition selects, and is removed from the
+// others along with its markers:
+//
+//	<!-- llmctxenv:if provider=claude,codex -->
+//	Instructions for Claude Code and Codex.
+//	<!-- llmctxenv:end -->
+//
+//	<!-- llmctxenv:if not provider=goose -->
+//	Instructions for every provider but Goose.
+//	<!-- llmctxenv:end -->
+//
+// A marker occupies a whole line. Sections can be nested, and a nested section is kept only if the enclosing
+// ones are kept.
+package preprocess
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Directive prefix of the markers in the HTML comments.
+const directivePrefix = "llmctxenv:"
+
+// Target is the provider the context file is processed for.
+type Target struct {
+	// Provider is the name of the provider.
+	Provider string
+
+	// Providers are the known provider names. A condition naming another provider is an error, unless
+	// Providers is empty.
+	Providers []string
+}
+
+// Error reports a malformed marker at a line of a context file.
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// section is an open conditional section.
+type section struct {
+	line int  // line number of the if marker
+	keep bool // whether the lines of the section are kept, including the enclosing sections
+}
+
+// Process returns the contents src of the context file named filename with the conditional sections which do
+// not apply to the target removed.
+//
+// It returns an [*Error] locating the marker if a marker is malformed, an end marker has no matching if
+// marker, or an if marker is not closed.
+func Process(filename string, src []byte, target Target) ([]byte, error) {
+	if !bytes.Contains(src, []byte(directivePrefix)) {
+		return src, nil
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(src))
+	var stack []section
+	for lineno, rest := 1, src; len(rest) > 0; lineno++ {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line, rest = rest[:i+1], rest[i+1:]
+		} else {
+			rest = nil
+		}
+
+		directive, ok := parseMarker(string(line))
+		if !ok {
+			if len(stack) == 0 || stack[len(stack)-1].keep {
+				out.Write(line)
+			}
+			continue
+		}
+
+		name, arg, _ := strings.Cut(directive, " ")
+		switch name {
+		case "if":
+			match, err := evaluate(strings.TrimSpace(arg), target)
+			if err != nil {
+				return nil, &Error{File: filename, Line: lineno, Msg: err.Error()}
+			}
+			keep := match && (len(stack) == 0 || stack[len(stack)-1].keep)
+			stack = append(stack, section{line: lineno, keep: keep})
+		case "end":
+			if strings.TrimSpace(arg) != "" {
+				return nil, &Error{File: filename, Line: lineno, Msg: fmt.Sprintf("unexpected %q after %send", strings.TrimSpace(arg), directivePrefix)}
+			}
+			if len(stack) == 0 {
+				return nil, &Error{File: filename, Line: lineno, Msg: fmt.Sprintf("%send without %sif", directivePrefix, directivePrefix)}
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			return nil, &Error{File: filename, Line: lineno, Msg: fmt.Sprintf("unknown directive %q", directivePrefix+name)}
+		}
+	}
+	if len(stack) > 0 {
+		open := stack[len(stack)-1]
+		return nil, &Error{File: filename, Line: open.line, Msg: fmt.Sprintf("%sif is not closed by %send", directivePrefix, directivePrefix)}
+	}
+
+	return out.Bytes(), nil
+}
+
+// FirstMarker returns the line number of the first marker in src, or false if src has no marker.
+//
+// It finds the markers of a context file which is not processed, so that they are reported rather than
+// kept verbatim.
+func FirstMarker(src []byte) (int, bool) {
+	if !bytes.Contains(src, []byte(directivePrefix)) {
+		return 0, false
+	}
+	for lineno, rest := 1, src; len(rest) > 0; lineno++ {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line, rest = rest[:i+1], rest[i+1:]
+		} else {
+			rest = nil
+		}
+		if _, ok := parseMarker(string(line)); ok {
+			return lineno, true
+		}
+	}
+	return 0, false
+}
+
+// parseMarker returns the directive of the marker on line, or false if line is not a marker.
+func parseMarker(line string) (string, bool) {
+	s := strings.TrimSpace(line)
+	s, ok := strings.CutPrefix(s, "<!--")
+	if !ok {
+		return "", false
+	}
+	s, ok = strings.CutSuffix(s, "-->")
+	if !ok {
+		return "", false
+	}
+	return strings.CutPrefix(strings.TrimSpace(s), directivePrefix)
+}
+
+// evaluate reports whether the condition of an if marker, "[not] provider=<name>[,<name>...]", selects the
+// target provider.
+func evaluate(cond string, target Target) (bool, error) {
+	negate := false
+	if rest, ok := strings.CutPrefix(cond, "not "); ok {
+		negate, cond = true, strings.TrimSpace(rest)
+	}
+
+	key, value, ok := strings.Cut(cond, "=")
+	if !ok || strings.TrimSpace(key) != "provider" {
+		return false, fmt.Errorf("want [not] provider=<name>[,<name>...], got %q", cond)
+	}
+
+	match := false
+	for name := range strings.SplitSeq(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return false, fmt.Errorf("empty provider name in %q", cond)
+		}
+		if len(target.Providers) > 0 && !slices.Contains(target.Providers, name) {
+			return false, fmt.Errorf("unknown provider %q", name)
+		}
+		if name == target.Provider {
+			match = true
+		}
+	}
+
+	return match != negate, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package preprocess_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zchee/llmctxenv/preprocess"
+)
+
+func TestProcess(t *testing.T) {
+	providers := []string{"claude", "codex", "gemini-cli", "goose"}
+
+	tests := map[string]struct {
+		src      string
+		provider string
+		want     string
+		wantErr  string
+	}{
+		"NoMarkers": {
+			src:      "# context\n",
+			provider: "claude",
+			want:     "# context\n",
+		},
+		"Keep": {
+			src:      "a\n<!-- llmctxenv:if provider=claude -->\nb\n<!-- llmctxenv:end -->\nc\n",
+			provider: "claude",
+			want:     "a\nb\nc\n",
+		},
+		"Strip": {
+			src:      "a\n<!-- llmctxenv:if provider=claude -->\nb\n<!-- llmctxenv:end -->\nc\n",
+			provider: "codex",
+			want:     "a\nc\n",
+		},
+		"List": {
+			src:      "<!-- llmctxenv:if provider=claude, codex -->\nb\n<!-- llmctxenv:end -->\n",
+			provider: "codex",
+			want:     "b\n",
+		},
+		"Not": {
+			src:      "<!-- llmctxenv:if not provider=claude,codex -->\nb\n<!-- llmctxenv:end -->\nc",
+			provider: "codex",
+			want:     "c",
+		},
+		"NotKeep": {
+			src:      "<!--llmctxenv:if not provider=claude-->\r\nb\r\n<!--llmctxenv:end-->\r\n",
+			provider: "goose",
+			want:     "b\r\n",
+		},
+		"Nested": {
+			src:      "<!-- llmctxenv:if provider=claude,codex -->\na\n  <!-- llmctxenv:if provider=codex -->\nb\n  <!-- llmctxenv:end -->\n<!-- llmctxenv:end -->\n",
+			provider: "claude",
+			want:     "a\n",
+		},
+		"NestedInStripped": {
+			src:      "<!-- llmctxenv:if provider=codex -->\n<!-- llmctxenv:if provider=claude -->\nb\n<!-- llmctxenv:end -->\n<!-- llmctxenv:end -->\n",
+			provider: "claude",
+			want:     "",
+		},
+		"InlineIsText": {
+			src:      "see <!-- llmctxenv:end --> here\n",
+			provider: "claude",
+			want:     "see <!-- llmctxenv:end --> here\n",
+		},
+		"OtherComment": {
+			src:      "<!-- llmctxenv is a tool -->\n",
+			provider: "claude",
+			want:     "<!-- llmctxenv is a tool -->\n",
+		},
+		"UnbalancedEnd": {
+			src:      "a\n<!-- llmctxenv:end -->\n",
+			provider: "claude",
+			wantErr:  "CONTEXT.md:2: llmctxenv:end without llmctxenv:if",
+		},
+		"NotClosed": {
+			src:      "<!-- llmctxenv:if provider=claude -->\n<!-- llmctxenv:if provider=codex -->\n<!-- llmctxenv:end -->\n",
+			provider: "claude",
+			wantErr:  "CONTEXT.md:1: llmctxenv:if is not closed by llmctxenv:end",
+		},
+		"UnknownProvider": {
+			src:      "\n\n<!-- llmctxenv:if provider=claud -->\n<!-- llmctxenv:end -->\n",
+			provider: "claude",
+			wantErr:  `CONTEXT.md:3: unknown provider "claud"`,
+		},
+		"MalformedCondition": {
+			src:      "<!-- llmctxenv:if claude -->\n<!-- llmctxenv:end -->\n",
+			provider: "claude",
+			wantErr:  `CONTEXT.md:1: want [not] provider=<name>[,<name>...], got "claude"`,
+		},
+		"EmptyName": {
+			src:      "<!-- llmctxenv:if provider=claude, -->\n<!-- llmctxenv:end -->\n",
+			provider: "claude",
+			wantErr:  `CONTEXT.md:1: empty provider name in "provider=claude,"`,
+		},
+		"UnknownDirective": {
+			src:      "<!-- llmctxenv:else -->\n",
+			provider: "claude",
+			wantErr:  `CONTEXT.md:1: unknown directive "llmctxenv:else"`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := preprocess.Process("CONTEXT.md", []byte(tt.src), preprocess.Target{Provider: tt.provider, Providers: providers})
+			if tt.wantErr != "" {
+				var perr *preprocess.Error
+				if !errors.As(err, &perr) || err.Error() != tt.wantErr {
+					t.Fatalf("Process() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Process() unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Process() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstMarker(t *testing.T) {
+	tests := map[string]struct {
+		src    string
+		want   int
+		wantOK bool
+	}{
+		"NoMarkers": {
+			src: "# context\n",
+		},
+		"Mentioned": {
+			src: "# context\nThe llmctxenv:if marker is processed in shared environments.\n",
+		},
+		"Marker": {
+			src:    "# context\n\n  <!-- llmctxenv:end -->",
+			want:   3,
+			wantOK: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := preprocess.FirstMarker([]byte(tt.src))
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("FirstMarker() = %d, %t, want %d, %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
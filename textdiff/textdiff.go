@@ -0,0 +1,191 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package textdiff implements a line oriented diff of text files and the unified diff format.
+package textdiff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Kind represents the kind of an [Op].
+type Kind int
+
+const (
+	Equal  Kind = iota // the lines are in both texts
+	Delete             // the lines are only in the old text
+	Insert             // the lines are only in the new text
+)
+
+// String returns a string representation of the [Kind].
+func (k Kind) String() string {
+	switch k {
+	case Equal:
+		return "equal"
+	case Delete:
+		return "delete"
+	case Insert:
+		return "insert"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Op is an edit operation which turns the lines a[A0:A1] of the old text into the lines b[B0:B1] of the new text.
+//
+// An [Equal] op has ranges of the same length, a [Delete] op has an empty B range
+// and an [Insert] op has an empty A range.
+type Op struct {
+	Kind   Kind
+	A0, A1 int
+	B0, B1 int
+}
+
+// Lines splits s into lines. Each line keeps its trailing newline except for the last line of a text
+// which does not end with a newline.
+func Lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Diff returns the shortest sequence of operations which turns a into b.
+//
+// Consecutive lines of the same [Kind] are coalesced into a single [Op], and the [Delete] op of a change
+// always precedes its [Insert] op.
+func Diff(a, b []string) []Op {
+	// Trim the common prefix and suffix which never take part in an edit
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	if prefix > 0 {
+		ops = append(ops, Op{Kind: Equal, A1: prefix, B1: prefix})
+	}
+	for _, op := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		op.A0, op.A1 = op.A0+prefix, op.A1+prefix
+		op.B0, op.B1 = op.B0+prefix, op.B1+prefix
+		ops = append(ops, op)
+	}
+	if suffix > 0 {
+		ops = append(ops, Op{Kind: Equal, A0: len(a) - suffix, A1: len(a), B0: len(b) - suffix, B1: len(b)})
+	}
+
+	return ops
+}
+
+// myers returns the operations which turn a into b using the O(ND) algorithm described in
+// "An O(ND) Difference Algorithm and Its Variations" by Eugene W. Myers.
+func myers(a, b []string) []Op {
+	n, m := len(a), len(b)
+	maxD := n + m
+	off := maxD + 1
+
+	// v[off+k] is the furthest x reached on the diagonal k
+	v := make([]int, 2*maxD+3)
+	// trace[d] holds v[off-d-1:off+d+2] at the start of the step d
+	var trace [][]int
+
+search:
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, slices.Clone(v[off-d-1:off+d+2]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk back the trace from the end of both texts, marking the lines of a and b which are kept
+	keptA, keptB := make([]bool, n), make([]bool, m)
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		tv := trace[d]
+		at := func(k int) int { return tv[k+d+1] }
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			keptA[x], keptB[y] = true, true
+		}
+		if d == 0 {
+			break
+		}
+		x, y = prevX, prevY
+	}
+
+	// Coalesce the lines into ops, emitting the deletion of each change before its insertion
+	var ops []Op
+	for x, y := 0, 0; x < n || y < m; {
+		switch {
+		case x < n && y < m && keptA[x] && keptB[y]:
+			x0, y0 := x, y
+			for x < n && y < m && keptA[x] && keptB[y] {
+				x++
+				y++
+			}
+			ops = append(ops, Op{Kind: Equal, A0: x0, A1: x, B0: y0, B1: y})
+		default:
+			x0, y0 := x, y
+			for x < n && !keptA[x] {
+				x++
+			}
+			for y < m && !keptB[y] {
+				y++
+			}
+			if x > x0 {
+				ops = append(ops, Op{Kind: Delete, A0: x0, A1: x, B0: y0, B1: y0})
+			}
+			if y > y0 {
+				ops = append(ops, Op{Kind: Insert, A0: x, A1: x, B0: y0, B1: y})
+			}
+		}
+	}
+
+	return ops
+}
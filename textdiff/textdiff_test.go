@@ -0,0 +1,289 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package textdiff_test
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zchee/llmctxenv/textdiff"
+)
+
+func TestLines(t *testing.T) {
+	tests := map[string]struct {
+		s    string
+		want []string
+	}{
+		"empty": {
+			s:    "",
+			want: nil,
+		},
+		"trailing newline": {
+			s:    "a\nb\n",
+			want: []string{"a\n", "b\n"},
+		},
+		"no trailing newline": {
+			s:    "a\nb",
+			want: []string{"a\n", "b"},
+		},
+		"empty lines": {
+			s:    "\n\n",
+			want: []string{"\n", "\n"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := textdiff.Lines(tt.s); !slices.Equal(got, tt.want) {
+				t.Errorf("Lines(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := map[string]struct {
+		a, b string
+		want []textdiff.Op
+	}{
+		"same": {
+			a: "a\nb\n",
+			b: "a\nb\n",
+			want: []textdiff.Op{
+				{Kind: textdiff.Equal, A0: 0, A1: 2, B0: 0, B1: 2},
+			},
+		},
+		"empty": {
+			a:    "",
+			b:    "",
+			want: nil,
+		},
+		"insert into empty": {
+			a: "",
+			b: "a\n",
+			want: []textdiff.Op{
+				{Kind: textdiff.Insert, A0: 0, A1: 0, B0: 0, B1: 1},
+			},
+		},
+		"replace": {
+			a: "a\nb\nc\n",
+			b: "a\nx\ny\nc\n",
+			want: []textdiff.Op{
+				{Kind: textdiff.Equal, A0: 0, A1: 1, B0: 0, B1: 1},
+				{Kind: textdiff.Delete, A0: 1, A1: 2, B0: 1, B1: 1},
+				{Kind: textdiff.Insert, A0: 2, A1: 2, B0: 1, B1: 3},
+				{Kind: textdiff.Equal, A0: 2, A1: 3, B0: 3, B1: 4},
+			},
+		},
+		"delete and insert": {
+			a: "a\nb\nc\nd\n",
+			b: "b\nc\nd\ne\n",
+			want: []textdiff.Op{
+				{Kind: textdiff.Delete, A0: 0, A1: 1, B0: 0, B1: 0},
+				{Kind: textdiff.Equal, A0: 1, A1: 4, B0: 0, B1: 3},
+				{Kind: textdiff.Insert, A0: 4, A1: 4, B0: 3, B1: 4},
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := textdiff.Diff(textdiff.Lines(tt.a), textdiff.Lines(tt.b))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff_Random(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	randomLines := func() []string {
+		lines := make([]string, r.IntN(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.IntN(4)))
+		}
+		return lines
+	}
+
+	for range 500 {
+		a, b := randomLines(), randomLines()
+		ops := textdiff.Diff(a, b)
+
+		// The ops must cover both texts in order and turn a into b
+		var got []string
+		var x, y, edits int
+		for _, op := range ops {
+			if op.A0 != x || op.B0 != y {
+				t.Fatalf("Diff(%q, %q) = %+v, op %+v does not continue at a[%d], b[%d]", a, b, ops, op, x, y)
+			}
+			switch op.Kind {
+			case textdiff.Equal:
+				if !slices.Equal(a[op.A0:op.A1], b[op.B0:op.B1]) {
+					t.Fatalf("Diff(%q, %q) = %+v, equal op %+v has different lines", a, b, ops, op)
+				}
+				got = append(got, a[op.A0:op.A1]...)
+			case textdiff.Delete:
+				edits += op.A1 - op.A0
+			case textdiff.Insert:
+				got = append(got, b[op.B0:op.B1]...)
+				edits += op.B1 - op.B0
+			}
+			x, y = op.A1, op.B1
+		}
+		if x != len(a) || y != len(b) || !slices.Equal(got, b) {
+			t.Fatalf("Diff(%q, %q) = %+v does not turn a into b", a, b, ops)
+		}
+
+		// The edit script must be the shortest one
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("Diff(%q, %q) has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+func TestUnified(t *testing.T) {
+	numbered := func(from, to int) string {
+		var sb strings.Builder
+		for i := from; i <= to; i++ {
+			fmt.Fprintf(&sb, "l%02d\n", i)
+		}
+		return sb.String()
+	}
+
+	tests := map[string]struct {
+		old, new string
+		want     string
+	}{
+		"same": {
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		"new file": {
+			old: "",
+			new: "a\nb\n",
+			want: `--- old
++++ new
+@@ -0,0 +1,2 @@
++a
++b
+`,
+		},
+		"removed file": {
+			old: "a\n",
+			new: "",
+			want: `--- old
++++ new
+@@ -1 +0,0 @@
+-a
+`,
+		},
+		"change with context": {
+			old: numbered(1, 10),
+			new: strings.Replace(numbered(1, 10), "l05\n", "five\n", 1),
+			want: `--- old
++++ new
+@@ -2,7 +2,7 @@
+ l02
+ l03
+ l04
+-l05
++five
+ l06
+ l07
+ l08
+`,
+		},
+		"separate hunks": {
+			old: numbered(1, 20),
+			new: strings.NewReplacer("l02\n", "", "l18\n", "l18\nnew\n").Replace(numbered(1, 20)),
+			want: `--- old
++++ new
+@@ -1,5 +1,4 @@
+ l01
+-l02
+ l03
+ l04
+ l05
+@@ -16,5 +15,6 @@
+ l16
+ l17
+ l18
++new
+ l19
+ l20
+`,
+		},
+		"joined hunks": {
+			old: numbered(1, 10),
+			new: strings.NewReplacer("l02\n", "", "l08\n", "").Replace(numbered(1, 10)),
+			want: `--- old
++++ new
+@@ -1,10 +1,8 @@
+ l01
+-l02
+ l03
+ l04
+ l05
+ l06
+ l07
+-l08
+ l09
+ l10
+`,
+		},
+		"no newline at end of file": {
+			old: "a\nb",
+			new: "a\nb\n",
+			want: `--- old
++++ new
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++b
+`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := textdiff.Unified("old", "new", tt.old, tt.new); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
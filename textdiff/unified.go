@@ -0,0 +1,121 @@
+// Copyright 2025 The llmctxenv Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change by [Unified].
+const DefaultContext = 3
+
+// Unified returns the unified diff of the old and new texts with [DefaultContext] lines of context,
+// or an empty string if they are the same.
+//
+// oldName and newName are the file names shown in the "---" and "+++" header lines.
+func Unified(oldName, newName, old, new string) string {
+	return UnifiedContext(oldName, newName, old, new, DefaultContext)
+}
+
+// UnifiedContext is like [Unified] but shows n lines of context around each change.
+func UnifiedContext(oldName, newName, old, new string, n int) string {
+	a, b := Lines(old), Lines(new)
+	ops := Diff(a, b)
+
+	var buf strings.Builder
+	for i := 0; i < len(ops); i++ {
+		if ops[i].Kind == Equal {
+			continue
+		}
+
+		// Extend the hunk over the unchanged runs which are short enough to join two changes
+		start, end := i, i+1
+		for end < len(ops) {
+			if ops[end].Kind != Equal {
+				end++
+				continue
+			}
+			if end+1 < len(ops) && ops[end].A1-ops[end].A0 <= 2*n {
+				end++
+				continue
+			}
+			break
+		}
+
+		a0, b0 := ops[start].A0, ops[start].B0
+		if start > 0 {
+			lead := min(n, ops[start-1].A1-ops[start-1].A0)
+			a0, b0 = a0-lead, b0-lead
+		}
+		a1, b1 := ops[end-1].A1, ops[end-1].B1
+		if end < len(ops) {
+			trail := min(n, ops[end].A1-ops[end].A0)
+			a1, b1 = a1+trail, b1+trail
+		}
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(a0, a1), hunkRange(b0, b1))
+
+		// Walk the ops covering the hunk and write each line with its prefix
+		for j := max(start-1, 0); j < min(end+1, len(ops)); j++ {
+			op := ops[j]
+			switch op.Kind {
+			case Equal:
+				for k := max(op.A0, a0); k < min(op.A1, a1); k++ {
+					writeLine(&buf, ' ', a[k])
+				}
+			case Delete:
+				for k := op.A0; k < op.A1; k++ {
+					writeLine(&buf, '-', a[k])
+				}
+			case Insert:
+				for k := op.B0; k < op.B1; k++ {
+					writeLine(&buf, '+', b[k])
+				}
+			}
+		}
+
+		i = end - 1
+	}
+
+	return buf.String()
+}
+
+// hunkRange formats the 0-based half-open line range [start, end) as a unified diff hunk range.
+func hunkRange(start, end int) string {
+	switch n := end - start; n {
+	case 0:
+		// An empty range refers to the line before it
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, n)
+	}
+}
+
+// writeLine writes a single line of a hunk with the prefix.
+func writeLine(buf *strings.Builder, prefix byte, line string) {
+	buf.WriteByte(prefix)
+	buf.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		buf.WriteString("\n\\ No newline at end of file\n")
+	}
+}